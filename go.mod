@@ -2,4 +2,11 @@ module notashelf.dev/hyprkeys
 
 go 1.19
 
-require github.com/oleiade/reflections v1.0.1 // indirect
+require (
+	github.com/fsnotify/fsnotify v1.6.0
+	github.com/oleiade/reflections v1.0.1
+	golang.org/x/term v0.5.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/sys v0.5.0 // indirect
@@ -1,215 +1,962 @@
 package main
 
 import (
-	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil" // io/ioutil is deprecated, use io and os packages instead
 	"os"
-	"regexp"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
 	"strings"
 
+	"golang.org/x/term"
+	flags "notashelf.dev/hyprkeys/util/cli"
+	keybinds "notashelf.dev/hyprkeys/util/keybinds"
 	parser "notashelf.dev/hyprkeys/util/parser"
+	tui "notashelf.dev/hyprkeys/util/tui"
+	watch "notashelf.dev/hyprkeys/util/watch"
 )
 
-// Read Hyprland configuration file and return lines that start with bind= and bindm=
-func readHyprlandConfig() ([]string, []string, []string, map[string]string) {
+// Version of hyprkeys, set at build time with
+// go build -ldflags "-X main.version=v0.1.0"
+var version = "dev"
+
+// Return the version string printed by --version, including the Go version
+// and the commit hyprkeys was built from when that information is available
+func versionString() string {
+	out := "hyprkeys " + version + " (" + runtime.Version()
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				out += ", commit " + setting.Value
+			}
+		}
+	}
+	return out + ")"
+}
+
+// Return the path of name in the user's config directory
+// XDG_CONFIG_HOME is checked first, falling back to $HOME/.config when it is unset or empty.
+// An empty string is returned if neither variable is available
+func userConfigPath(name ...string) string {
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		home := os.Getenv("HOME")
+		if home == "" {
+			return ""
+		}
+		configHome = filepath.Join(home, ".config")
+	}
+	return filepath.Join(append([]string{configHome}, name...)...)
+}
+
+// Return the default location of hyprland.conf
+func defaultConfigPath() string {
+	return userConfigPath("hypr", "hyprland.conf")
+}
+
+// Return the path of the file holding the default options of hyprkeys, see flags.ReadDefaults
+// HYPRKEYS_DEFAULTS is checked first, then the hyprkeys config directory
+func defaultsPath() string {
+	if path := os.Getenv("HYPRKEYS_DEFAULTS"); path != "" {
+		return keybinds.ExpandPath(path)
+	}
+	return userConfigPath("hyprkeys", "config")
+}
+
+// Return the path of the snapshot saved by the snapshot subcommand
+func snapshotPath() string {
+	return userConfigPath("hyprkeys", "snapshot.json")
+}
+
+// Return the paths of the configs to read, the first of these that is set wins:
+//  1. -c/--config FILE followed by the configs passed as arguments, a lone "-" reads one from stdin
+//  2. --test, shorthand for --config test/hyprland.conf
+//  3. the config set in the defaults file, see defaultsPath
+//  4. the HYPRKEYS_CONFIG environment variable, then HYPRLAND_CONFIG
+//  5. the default config path, see defaultConfigPath
+func configPathsFromFlags(f *flags.Flags) []string {
+	var paths []string
+	if f.Config != "" {
+		paths = append(paths, keybinds.ExpandPath(f.Config))
+	}
+	for _, arg := range f.Args {
+		paths = append(paths, keybinds.ExpandPath(arg))
+	}
+	if len(paths) > 0 {
+		return paths
+	}
+	if f.Test {
+		return []string{"test/hyprland.conf"}
+	}
+	if f.DefaultConfig != "" {
+		return []string{keybinds.ExpandPath(f.DefaultConfig)}
+	}
+	for _, env := range []string{"HYPRKEYS_CONFIG", "HYPRLAND_CONFIG"} {
+		if path := os.Getenv(env); path != "" {
+			return []string{keybinds.ExpandPath(path)}
+		}
+	}
+	return []string{defaultConfigPath()}
+}
 
-	// If --test flag is passed, read from test file
-	// otherwise read from ~/.config/hypr/hyprland.conf
-	var configPath string
-	if len(os.Args) > 1 && os.Args[1] == "--test" {
-		configPath = "test/hyprland.conf"
+// Return the path --blocks writes the regenerated config to
+// Without --blocks-output FILE this is the input path with "-generated" added before the extension
+func blocksOutputPath(f *flags.Flags, configPath string) string {
+	if f.BlocksOut != "" {
+		return f.BlocksOut
+	}
+	if configPath == "-" {
+		return "hyprland-generated.conf"
+	}
+	// The regenerated config isn't compressed, it shouldn't be named like it is
+	configPath = strings.TrimSuffix(configPath, ".gz")
+	ext := filepath.Ext(configPath)
+	return strings.TrimSuffix(configPath, ext) + "-generated" + ext
+}
+
+// Width the plain table is fit into when it can't be told how wide the terminal is
+const defaultWidth = 80
+
+// Return the width the plain table is fit into, the first of these that is known wins:
+//  1. --width N
+//  2. the width of the terminal stdout is connected to
+//  3. the width of the terminal stderr is connected to, when stdout is piped into a pager like less -R
+//  4. the COLUMNS environment variable
+//  5. defaultWidth, e.g. when the output is piped
+func outputWidth(f *flags.Flags) int {
+	if f.Width >= 0 {
+		return f.Width
+	}
+	if printsToTerminal(f) {
+		if width, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && width > 0 {
+			return width
+		}
+	}
+	// A pager shows the output on the terminal the pipe started from, which stderr is still connected to
+	if f.Output == "" && term.IsTerminal(int(os.Stderr.Fd())) {
+		if width, _, err := term.GetSize(int(os.Stderr.Fd())); err == nil && width > 0 {
+			return width
+		}
+	}
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+	return defaultWidth
+}
+
+// Return true if the plain table should be colored
+// With --color=auto it is colored when printing to a terminal, unless NO_COLOR is set
+func useColor(f *flags.Flags) bool {
+	switch f.Color {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return printsToTerminal(f)
+}
+
+// Return true if the output goes to a terminal, rather than a pipe or an --output file
+func printsToTerminal(f *flags.Flags) bool {
+	return f.Output == "" && term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// Return where the output is written to, stdout or the --output file
+// Directories leading up to the file are created if they don't exist yet
+func openOutput(f *flags.Flags) (io.WriteCloser, error) {
+	if f.Output == "" {
+		return nopCloser{os.Stdout}, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(f.Output), 0755); err != nil {
+		return nil, err
+	}
+	return os.Create(f.Output)
+}
+
+// Writer whose Close does nothing, so stdout isn't closed along with an --output file
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// Contents of stdin, kept around after the first read since stdin can only be read once
+var stdinConfig []byte
+
+// Return the contents of the config at configPath, "-" reads it from stdin instead
+// Gzip compressed configs are decompressed
+func readConfigContent(configPath string) ([]byte, error) {
+	if configPath != "-" {
+		content, err := ioutil.ReadFile(configPath)
+		if err != nil {
+			return nil, err
+		}
+		return keybinds.Decompress(content)
+	}
+	if stdinConfig == nil {
+		content, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		if content, err = keybinds.Decompress(content); err != nil {
+			return nil, err
+		}
+		stdinConfig = content
+	}
+	return stdinConfig, nil
+}
+
+// Read the binds and variables of the config at configPath, following source= includes
+// Problems that didn't stop it from being read are printed as warnings
+func readHyprlandConfig(configPath string) (keybinds.Config, error) {
+	if configPath == "" {
+		return keybinds.Config{}, errors.New("could not locate the config: neither XDG_CONFIG_HOME nor HOME is set")
+	}
+	var config keybinds.Config
+	if configPath != "-" {
+		var err error
+		if config, err = keybinds.Read(configPath); err != nil {
+			return config, err
+		}
 	} else {
-		configPath = os.Getenv("HOME") + "/.config/hypr/hyprland.conf"
+		content, err := readConfigContent(configPath)
+		if err != nil {
+			return keybinds.Config{}, fmt.Errorf("reading config: %w", err)
+		}
+		if config, err = keybinds.Parse(content); err != nil {
+			return config, err
+		}
 	}
+	for _, warning := range config.Warnings {
+		fmt.Fprintln(os.Stderr, "Warning:", warning)
+	}
+	return config, nil
+}
 
-	// Open the file
-	file, err := os.Open(configPath)
-	if err != nil {
-		fmt.Println("Error opening file:", err)
-		os.Exit(1)
+// Read the configs at configPaths as one, see readHyprlandConfig and keybinds.Merge
+// Two configs may define the same variable differently, so with more than one
+// each has its variables substituted into its own binds first, unless rawVars is set
+func readHyprlandConfigs(configPaths []string, rawVars bool) (keybinds.Config, error) {
+	if len(configPaths) == 1 {
+		return readHyprlandConfig(configPaths[0])
+	}
+	var configs []keybinds.Config
+	for _, path := range configPaths {
+		config, err := readHyprlandConfig(path)
+		if err != nil {
+			return keybinds.Config{}, err
+		}
+		// Cycles are reported once by run, which resolves the variables of the merged config again
+		if !rawVars {
+			variables, _ := keybinds.ResolveVariables(config.Variables)
+			keybinds.SubstituteVariables(config.Keybinds, variables)
+			keybinds.SubstituteRuleVariables(config.Rules, variables)
+		}
+		configs = append(configs, config)
 	}
-	defer file.Close()
+	return keybinds.Merge(configs...), nil
+}
 
-	scanner := bufio.NewScanner(file)
-	vm := make(map[string]string)
+// Resolve the variables of a config, see keybinds.ResolveVariables, with a warning for each cycle
+func resolveVariables(variableMap map[string]string) map[string]string {
+	variables, cycles := keybinds.ResolveVariables(variableMap)
+	for _, err := range cycles {
+		fmt.Fprintln(os.Stderr, "Warning:", err)
+	}
+	return variables
+}
 
-	var kbKeybinds []string
-	var mKeybinds []string
-	var variables []string
-	var variableMap = vm
+// Print the help message to w
+func printHelp(w io.Writer) {
+	fmt.Fprintln(w, "Usage: hyprkeys [OPTIONS] [CONFIG...]")
+	fmt.Fprintln(w, "       hyprkeys --diff [OPTIONS] OLD NEW")
+	fmt.Fprintln(w, "       hyprkeys validate [OPTIONS] [CONFIG...]")
+	fmt.Fprintln(w, "       hyprkeys schema")
+	fmt.Fprintln(w, "       hyprkeys snapshot [OPTIONS] [CONFIG...]")
+	fmt.Fprintln(w, "       hyprkeys completion bash|zsh|fish")
+	fmt.Fprintln(w, "Print the keybinds of a Hyprland configuration file as a table, or in one of the formats below.")
+	fmt.Fprintln(w, "If no file is specified, the default configuration file is used.")
+	fmt.Fprintln(w, "The binds of several files are printed together. Pass - to read the configuration from stdin.")
+	fmt.Fprintln(w, "Default options can be written one per line in ~/.config/hyprkeys/config, e.g. `color = always`.")
+	fmt.Fprintln(w, "Options:")
+	flags.PrintOptions(w)
+	fmt.Fprintln(w, "Exit status:")
+	fmt.Fprintln(w, "  0  success")
+	fmt.Fprintln(w, "  1  invalid options or arguments")
+	fmt.Fprintln(w, "  2  the config couldn't be read, or the output couldn't be written")
+	fmt.Fprintln(w, "  3  validate found problems, the binds differ with --diff or --since, --conflicts found some,")
+	fmt.Fprintln(w, "     malformed binds were skipped with --strict, or no bind matched --grep")
+}
 
-	for scanner.Scan() {
-		line := scanner.Text()
+// Exit statuses of hyprkeys, the same in every mode since scripts rely on them
+const (
+	exitOK       = 0 // success
+	exitUsage    = 1 // invalid options or arguments
+	exitConfig   = 2 // the config couldn't be read, or the output couldn't be written
+	exitProblems = 3 // a check failed: validate found problems, binds differ, conflicts, or nothing matched --grep
+)
+
+func main() {
+	// Options from the defaults file are read first, so the command line overrides them
+	defaults, err := flags.ReadDefaults(defaultsPath())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: reading defaults:", err)
+		os.Exit(exitUsage)
+	}
+	args, err := flags.ReadFlags(defaults, os.Args[1:])
+	if err != nil {
+		// The help goes to stderr along with the error, so it never ends up in a pipe
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		printHelp(os.Stderr)
+		os.Exit(exitUsage)
+	}
 
-		matched, err := regexp.MatchString("^bind.*[lrme]*.*=", line)
-		// TODO: regexp.Compile() instead of regexp.MatchString()
+	// The version doesn't depend on the config, so print it before trying to read one
+	if args.Version {
+		fmt.Println(versionString())
+		return
+	}
 
+	if args.Help {
+		printHelp(os.Stdout)
+		return
+	}
+
+	if len(args.Args) > 0 && args.Args[0] == "completion" {
+		if len(args.Args) != 2 {
+			fmt.Fprintln(os.Stderr, "Error: completion needs a shell, one of", strings.Join(flags.CompletionShells, ", "))
+			os.Exit(exitUsage)
+		}
+		if err := flags.WriteCompletion(os.Stdout, args.Args[1]); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(exitUsage)
+		}
+		return
+	}
+
+	if len(args.Args) > 0 && args.Args[0] == "schema" {
+		schema, err := keybinds.Schema()
 		if err != nil {
-			panic(err)
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(exitConfig)
 		}
+		fmt.Printf("%s\n", schema)
+		return
+	}
+
+	if len(args.Args) > 0 && args.Args[0] == "validate" {
+		os.Exit(runValidate(args))
+	}
+
+	if len(args.Args) > 0 && args.Args[0] == "snapshot" {
+		os.Exit(runSnapshot(args))
+	}
+
+	if args.Diff {
+		os.Exit(runDiff(args))
+	}
+
+	if args.Since {
+		os.Exit(runSince(args))
+	}
 
-		if matched {
-			// If the line starts with any bind type, append it to the keybinds slice
-			mKeybinds = append(mKeybinds, line)
+	configPaths := configPathsFromFlags(args)
+	// --blocks only regenerates the first config
+	configPath := configPaths[0]
 
-		} else if strings.HasPrefix(line, "$") {
-			// Probably not the best way to do this, but can't think of another occasion where a line would start with "$"
-			// and include "=", yet still not be a variable
-			if strings.Contains(line, "=") {
-				// Store variables and their values in a map
-				// This will be used to replace variables in the markdown table
-				// with their values
-				variable := strings.SplitN(line, "=", 2)
-				variableMap[variable[0]] = variable[1]
+	if args.Watch {
+		for _, path := range configPaths {
+			if path == "-" {
+				fmt.Fprintln(os.Stderr, "Error: --watch needs a config file, it can't watch stdin")
+				os.Exit(exitUsage)
+			}
+		}
+		err := watch.Watch(func() []string {
+			// Start every run on a clean screen, like watch(1) does
+			if printsToTerminal(args) {
+				fmt.Print("\x1b[H\x1b[2J")
+			}
+			config, err := readHyprlandConfigs(configPaths, args.RawVars)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				return configPaths
 			}
+			if _, err := runToOutput(args, configPath, config); err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+			}
+			return config.Files
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(exitConfig)
 		}
+		return
+	}
+
+	config, err := readHyprlandConfigs(configPaths, args.RawVars)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(exitConfig)
+	}
+	if len(args.Formats) > 0 {
+		os.Exit(runFormats(args, configPath, config))
+	}
+	status, err := runToOutput(args, configPath, config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(exitConfig)
+	}
+	os.Exit(status)
+}
+
+// Print the problems of the config, see keybinds.Validate, and return the exit status
+// It is exitProblems if there are some
+func runValidate(args *flags.Flags) int {
+	// The configs to validate follow the subcommand
+	args.Args = args.Args[1:]
+	config, err := readHyprlandConfigs(configPathsFromFlags(args), true)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitConfig
+	}
+	problems := keybinds.Validate(config)
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if len(problems) > 0 {
+		if len(problems) == 1 {
+			fmt.Fprintln(os.Stderr, "1 problem found")
+		} else {
+			fmt.Fprintf(os.Stderr, "%d problems found\n", len(problems))
+		}
+		return exitProblems
+	}
+	return exitOK
+}
+
+// Save the binds of the configs following the subcommand to the snapshot file, for --since to compare with
+func runSnapshot(args *flags.Flags) int {
+	args.Args = args.Args[1:]
+	config, err := readHyprlandConfigs(configPathsFromFlags(args), args.RawVars)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitConfig
+	}
+	if !args.RawVars {
+		keybinds.SubstituteVariables(config.Keybinds, resolveVariables(config.Variables))
+	}
+	keybinds.Normalize(config.Keybinds)
+
+	path := snapshotPath()
+	if path == "" {
+		fmt.Fprintln(os.Stderr, "Error: can't find the config directory, neither XDG_CONFIG_HOME nor HOME is set")
+		return exitConfig
+	}
+	data, err := keybinds.JSON(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitConfig
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		fmt.Fprintln(os.Stderr, "Error: saving the snapshot:", err)
+		return exitConfig
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, "Error: saving the snapshot:", err)
+		return exitConfig
+	}
+	fmt.Fprintf(os.Stderr, "Saved %d binds to %s\n", len(config.Keybinds), path)
+	return exitOK
+}
+
+// Print the binds that changed since the snapshot was saved and return the exit status, like runDiff
+func runSince(args *flags.Flags) int {
+	data, err := os.ReadFile(snapshotPath())
+	if os.IsNotExist(err) {
+		fmt.Fprintln(os.Stderr, "Error: no snapshot to compare with, save one with `hyprkeys snapshot`")
+		return exitConfig
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: reading the snapshot:", err)
+		return exitConfig
+	}
+	var snapshot []keybinds.Keybind
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		fmt.Fprintln(os.Stderr, "Error: reading the snapshot:", err)
+		return exitConfig
+	}
 
+	config, err := readHyprlandConfigs(configPathsFromFlags(args), args.RawVars)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitConfig
 	}
+	if !args.RawVars {
+		keybinds.SubstituteVariables(config.Keybinds, resolveVariables(config.Variables))
+	}
+	keybinds.Normalize(config.Keybinds)
+	return printDiff(args, keybinds.Compare(snapshot, config.Keybinds))
+}
 
-	if err := scanner.Err(); err != nil {
-		panic(err)
+// Print the binds that differ between the two configs passed as arguments and return the exit status
+// It is exitOK if they bind the same and exitProblems if they differ
+func runDiff(args *flags.Flags) int {
+	if len(args.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "Error: --diff needs two configs to compare, OLD and NEW")
+		return exitUsage
 	}
 
-	return kbKeybinds, mKeybinds, variables, variableMap
+	var configs [2]keybinds.Config
+	for i, path := range args.Args {
+		config, err := readHyprlandConfig(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return exitConfig
+		}
+		if !args.RawVars {
+			keybinds.SubstituteVariables(config.Keybinds, resolveVariables(config.Variables))
+		}
+		keybinds.Normalize(config.Keybinds)
+		configs[i] = config
+	}
+	return printDiff(args, keybinds.Compare(configs[0].Keybinds, configs[1].Keybinds))
 }
 
-// Return each keybind as a markdown table row
-// like this: | <kbd>SUPER + L</kbd> | firefox | , firefox
-// we also account for no MOD key.
+// Print a diff, as JSON with --json, and return the exit status of runDiff
+func printDiff(args *flags.Flags, diff keybinds.Diff) int {
+	out, err := openOutput(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: could not write the output:", err)
+		return exitConfig
+	}
+	if args.JSON {
+		data, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+		fmt.Fprintf(out, "%s\n", data)
+	} else if err := keybinds.WriteDiff(out, diff); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+	}
+	if err := out.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error: could not write the output:", err)
+		return exitConfig
+	}
 
-// Pass both kbKeybinds and mKeybinds to this function
-func keybindsToMarkdown(kbKeybinds, mKeybinds []string) []string {
-	var markdown []string
-	for _, keybind := range kbKeybinds {
-		keybind = strings.TrimPrefix(keybind, "bind=")
+	if !diff.Empty() {
+		return exitProblems
+	}
+	return exitOK
+}
 
-		// Split "keybind" into a slice of strings
-		// based on the comma delimiter
-		keybindSlice := strings.SplitN(keybind, ",", 4)
+// Print the window rules of the config, as a markdown table with --markdown and a plain one otherwise
+func printRules(out io.Writer, args *flags.Flags, rules []keybinds.WindowRule) int {
+	opts := keybinds.TableOptions{NoHeader: args.NoHeader}
+	if args.Markdown {
+		for _, row := range keybinds.MarkdownRules(rules, opts) {
+			fmt.Fprintln(out, row)
+		}
+	} else if err := keybinds.WriteRules(out, rules, opts); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitConfig
+	}
+	return exitOK
+}
 
-		// Trim whitespace from keybindSlice[1] to keybindSlice[3]
-		keybindSlice[1] = strings.TrimSpace(keybindSlice[1])
-		keybindSlice[2] = strings.TrimSpace(keybindSlice[2])
-		keybindSlice[3] = strings.TrimSpace(keybindSlice[3])
+// Print the combinations --only-unbound checks that none of the binds is triggered by, one per line
+func printUnbound(out io.Writer, args *flags.Flags, binds []keybinds.Keybind) int {
+	mods, keys := args.UnboundMods, args.UnboundKeys
+	if len(mods) == 0 {
+		mods = keybinds.DefaultUnboundMods
+	}
+	if len(keys) == 0 {
+		keys = keybinds.DefaultUnboundKeys
+	}
+	keys, err := keybinds.ExpandKeys(keys)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: invalid --unbound-keys:", err)
+		return exitUsage
+	}
+	separator := args.Separator
+	if separator == "" {
+		separator = keybinds.DefaultSeparator
+	}
+	for _, kb := range keybinds.Unbound(binds, mods, keys) {
+		fmt.Fprintln(out, kb.KeysWith(separator))
+	}
+	return exitOK
+}
 
-		// Print the keybind as a markdown table row
+// Write the config in each of the --format formats, to its file in --output-dir or the --output file in the same position
+// The config is only read once, every format gets its own copy of the binds since run changes them
+func runFormats(args *flags.Flags, configPath string, config keybinds.Config) int {
+	var outputs []string
+	switch {
+	case args.OutputDir != "" && args.Output != "":
+		fmt.Fprintln(os.Stderr, "Error: --format writes either into --output-dir or to --output files, not both")
+		return exitUsage
+	case args.OutputDir != "":
+		for _, format := range args.Formats {
+			outputs = append(outputs, flags.FormatFile(args.OutputDir, format))
+		}
+	case args.Output != "":
+		outputs = strings.Split(args.Output, ",")
+		if len(outputs) != len(args.Formats) {
+			fmt.Fprintf(os.Stderr, "Error: --format has %d formats but --output names %d files, pass a file for each format\n", len(args.Formats), len(outputs))
+			return exitUsage
+		}
+	case len(args.Formats) == 1:
+		outputs = []string{""}
+	default:
+		fmt.Fprintln(os.Stderr, "Error: --format with several formats needs --output-dir or an --output file for each format")
+		return exitUsage
+	}
 
-		// Check if keybindSlice is empty
-		// Trim the whitespace and "+" if it is
-		if keybindSlice[0] == "" {
-			keybindSlice[1] = strings.TrimSpace(keybindSlice[1])
-			markdown = append(markdown, "| <kbd>"+keybindSlice[1]+"</kbd> | "+keybindSlice[2]+" | "+keybindSlice[3]+" |")
+	for i, format := range args.Formats {
+		copied := config
+		copied.Keybinds = append([]keybinds.Keybind(nil), config.Keybinds...)
+		copied.Rules = append([]keybinds.WindowRule(nil), config.Rules...)
+		status, err := runToOutput(args.ForFormat(format, outputs[i]), configPath, copied)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return exitConfig
+		}
+		if status != exitOK {
+			return status
+		}
+	}
+	return exitOK
+}
 
+// Open the output and run, returning the exit status of run
+// or an error if the output couldn't be written
+func runToOutput(args *flags.Flags, configPath string, config keybinds.Config) (int, error) {
+	out, err := openOutput(args)
+	if err != nil {
+		return 0, fmt.Errorf("could not write the output: %w", err)
+	}
+	status := run(out, args, configPath, config)
+	if err := out.Close(); err != nil {
+		return 0, fmt.Errorf("could not write the output: %w", err)
+	}
+	return status, nil
+}
+
+// Print the binds of config to out in the formats selected by args and return the exit status
+func run(out io.Writer, args *flags.Flags, configPath string, config keybinds.Config) int {
+	// Binds that couldn't be parsed are left out, --strict refuses to print anything without them
+	for _, err := range config.Errors {
+		if args.Strict {
+			fmt.Fprintln(os.Stderr, "Error:", err)
 		} else {
-			markdown = append(markdown, "| <kbd>"+keybindSlice[0]+" + "+keybindSlice[1]+"</kbd> | "+keybindSlice[2]+" | "+keybindSlice[3]+" |")
+			fmt.Fprintln(os.Stderr, "Warning: skipping bind,", err)
 		}
 	}
+	if args.Strict && len(config.Errors) > 0 {
+		return exitProblems
+	}
 
-	for _, keybind := range mKeybinds {
-		keybind = strings.TrimPrefix(keybind, "bindm=")
+	// Variables are resolved once, everything below uses them
+	// Their cycles are only worth a warning when they are substituted
+	variables, cycles := keybinds.ResolveVariables(config.Variables)
 
-		// Split "keybind" into a slice of strings
-		// based on the comma delimiter
-		keybindSlice := strings.SplitN(keybind, ",", 3)
+	// Variables are substituted before formatting, so every output mode shows their values
+	// --raw-vars keeps them as written, and wins over --variables
+	if !args.RawVars {
+		for _, err := range cycles {
+			fmt.Fprintln(os.Stderr, "Warning:", err)
+		}
+		// Substitution leaves a variable that isn't defined as written, which is easy to miss in the output
+		for _, problem := range keybinds.UndefinedVariables(config) {
+			fmt.Fprintln(os.Stderr, "Warning:", problem)
+		}
+		keybinds.SubstituteVariables(config.Keybinds, variables)
+		keybinds.SubstituteRuleVariables(config.Rules, variables)
+	}
+	// Commands are only expanded, never run. Config variables are expanded in them even with --raw-vars
+	if args.ResolveExec {
+		keybinds.ResolveExec(config.Keybinds, variables)
+	}
+	keybinds.Normalize(config.Keybinds)
 
-		// Trim whitespace from keybindSlice[1] to keybindSlice[2]
-		keybindSlice[1] = strings.TrimSpace(keybindSlice[1])
-		keybindSlice[2] = strings.TrimSpace(keybindSlice[2])
+	if args.Rules {
+		return printRules(out, args, config.Rules)
+	}
 
-		// Print the keybind as a markdown table row
+	// The same bind copied into several sourced files only needs to show up once
+	if !args.NoDedup {
+		config.Keybinds = keybinds.Dedup(config.Keybinds)
+	}
 
-		// Check if keybindSlice[0] is null
-		// Trim the whitespace and "+" if it is
-		if keybindSlice[0] == "" {
-			markdown = append(markdown, "| <kbd>"+keybindSlice[1]+"</kbd> | | "+keybindSlice[2]+" |")
-		} else {
-			// put "| |" inbetween the keybindSlice[0] and keybindSlice[1]
-			markdown = append(markdown, "| <kbd>"+keybindSlice[0]+" + "+keybindSlice[1]+"</kbd> | | "+keybindSlice[2]+" |")
+	// The free combinations depend on every bind, so the filters don't apply
+	if args.OnlyUnbound {
+		return printUnbound(out, args, config.Keybinds)
+	}
+
+	// Filters apply before formatting, so they work the same for every output mode
+	if len(args.FilterMods) > 0 {
+		config.Keybinds = keybinds.FilterByMods(config.Keybinds, args.FilterMods, variables)
+	}
+	if len(args.FilterDispatchers) > 0 {
+		config.Keybinds = keybinds.FilterByDispatchers(config.Keybinds, args.FilterDispatchers)
+	}
+	if len(args.FilterDevices) > 0 {
+		config.Keybinds = keybinds.FilterByDevices(config.Keybinds, args.FilterDevices)
+	}
+	// Excludes apply after the filters, so they narrow down what those picked
+	if len(args.ExcludeMods) > 0 {
+		config.Keybinds = keybinds.ExcludeMods(config.Keybinds, args.ExcludeMods, variables)
+	}
+	if len(args.ExcludeDispatchers) > 0 {
+		config.Keybinds = keybinds.ExcludeDispatchers(config.Keybinds, args.ExcludeDispatchers)
+	}
+	if args.Grep != "" {
+		matches, err := keybinds.GrepMatcher(args.Grep, args.GrepRegex)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error: invalid --grep pattern:", err)
+			return exitUsage
+		}
+		config.Keybinds = keybinds.FilterByMatch(config.Keybinds, matches)
+		// Say so instead of printing an empty table, a count of 0 says it already
+		if len(config.Keybinds) == 0 && !args.Count {
+			fmt.Fprintln(os.Stderr, "No binds match "+args.Grep)
+			return exitProblems
 		}
+	}
 
+	// --count is meant for scripts, so it prints the number and nothing else
+	if args.Count {
+		fmt.Fprintln(out, len(config.Keybinds))
+		return exitOK
 	}
 
-	return markdown
-}
+	if args.Sort != "" {
+		if err := keybinds.Sort(config.Keybinds, args.Sort); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return exitUsage
+		}
+	}
 
-func main() {
-	kbKeybinds, mKeybinds, variables, variableMap := readHyprlandConfig()
-
-	// If the first argument is empty, show the help message
-	if len(os.Args) == 1 {
-		fmt.Println("Usage: hyprkeys [OPTIONS]")
-		fmt.Println("Generate a markdown table of keybinds from a Hyprland configuration file.")
-		fmt.Println("If no file is specified, the default configuration file is used.")
-		fmt.Println("Options:")
-		fmt.Println("  -h, --help\t\tShow this help message")
-		fmt.Println("  -t, --test\t\tUse the test configuration file")
-		fmt.Println("  -m, --markdown\t\tPrint the binds as a markdown table")
-		fmt.Println("  -v, --verbose\t\tPrint text as is, without making it pretty")
-		fmt.Println("  -V, --version\t\tShow the version number")
-	} else if len(os.Args) > 1 {
-		// Print args
-
-		// If --verbose is passed as an argument, print the keybinds
-		// to the terminal
-		if os.Args[1] == "--verbose" {
-			for _, keybind := range kbKeybinds {
-				fmt.Println(keybind)
+	// Modifiers are replaced last, so filtering and sorting still see their names
+	if args.Pretty {
+		symbols, err := keybinds.ParseSymbols(args.Symbols)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error: invalid --symbols:", err)
+			return exitUsage
+		}
+		keybinds.PrettyMods(config.Keybinds, symbols)
+	}
 
-			}
-			for _, keybind := range mKeybinds {
-				println(keybind)
+	// Comments are only shown as descriptions when asked for,
+	// binds given a description with bindd= always show it
+	if args.Comments {
+		for i := range config.Keybinds {
+			if config.Keybinds[i].Description == "" {
+				config.Keybinds[i].Description = config.Keybinds[i].Comment
 			}
 		}
+	}
 
-		// If --markdown is passed as an argument, print the keybinds
-		// as a markdown table
-		if os.Args[1] == "--markdown" {
-			markdown := keybindsToMarkdown(kbKeybinds, mKeybinds)
-			println("| Keybind | Dispatcher | Command |")
-			println("|---------|------------|---------|")
-			for _, row := range markdown {
-				println(row)
-			}
+	// If --verbose is passed as an argument, print the keybinds
+	// to the terminal
+	if args.Verbose {
+		for _, keybind := range config.Keybinds {
+			fmt.Fprintln(out, keybind.Line)
 		}
+	}
 
-		if os.Args[1] == "--variables" {
-			for _, variable := range variables {
-				println(variable)
-			}
+	// The browser needs a terminal to read keys from and draw on, without one the plain table is printed
+	if args.TUI && !args.ModeSelected() && printsToTerminal(args) && term.IsTerminal(int(os.Stdin.Fd())) {
+		if err := tui.Run(config.Keybinds); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return exitConfig
+		}
+		return exitOK
+	}
 
-			// Now we replace the variables in the markdown table with their values
-			// and print the table if --markdown is also passed as an argument
-			markdown := keybindsToMarkdown(kbKeybinds, mKeybinds)
-			println("| Keybind | Dispatcher | Command |")
-			println("|---------|------------|---------|")
-			for _, row := range markdown {
-				for key, value := range variableMap {
-
-					row = strings.ReplaceAll(row, key, value)
-				}
-				println(row)
-			}
+	tableOpts := keybinds.TableOptions{NoHeader: args.NoHeader, Columns: args.Columns, Separator: args.Separator, ShowFlags: args.ShowFlags, KbdStyle: args.KbdStyle, GroupBy: args.GroupBy, MarkRepeats: args.MarkRepeats}
+
+	// Without an option selecting the output, print an aligned table
+	// that reads well in the terminal
+	if !args.ModeSelected() {
+		opts := keybinds.PlainOptions{TableOptions: tableOpts, Width: outputWidth(args), Color: useColor(args)}
+		if err := keybinds.WritePlain(out, config, opts); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
 		}
+	}
 
-		if os.Args[1] == "--blocks" {
-			file, err := ioutil.ReadFile("test/hyprland.conf") // TODO: make this use configPath
-			if err != nil {
-				panic(err)
-			}
-			content := string(file)
-			config := parser.Parse(content)
-			data, err := json.MarshalIndent(config, "", "  ")
+	// If --markdown is passed as an argument, print the keybinds
+	// as a markdown table
+	if args.Markdown {
+		for _, row := range keybinds.Markdown(config, tableOpts) {
+			fmt.Fprintln(out, row)
+		}
+	}
+
+	// If --json is passed as an argument, print the keybinds
+	// as an array of JSON objects
+	// With --stats it prints the stats as JSON instead
+	if args.JSON && !args.Stats {
+		data, err := keybinds.JSON(config)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+		fmt.Fprintf(out, "%s\n", data)
+	}
+
+	// If --yaml is passed as an argument, print the keybinds
+	// as a YAML list, in the order they appear in the config
+	if args.YAML {
+		data, err := keybinds.YAML(config)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+		fmt.Fprintf(out, "%s", data)
+	}
+
+	// If --csv is passed as an argument, print the keybinds
+	// as CSV, e.g. for importing into a spreadsheet
+	if args.CSV {
+		if err := keybinds.WriteCSV(out, config, tableOpts); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+	}
+
+	// If --rofi is passed as an argument, print a line per bind
+	// that can be piped into rofi -dmenu
+	if args.Rofi {
+		if err := keybinds.WriteRofi(out, config); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+	}
+
+	// If --keys-only is passed as an argument, print the combinations that are bound,
+	// e.g. for a keyboard layout visualizer
+	if args.KeysOnly {
+		if err := keybinds.WriteKeys(out, config, args.Separator); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+	}
+
+	// If --man is passed as an argument, print the binds
+	// as a man page, e.g. for hyprkeys --man | man -l -
+	if args.Man {
+		if err := keybinds.WriteMan(out, config); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+	}
+
+	// If --widget-json is passed as an argument, print the binds
+	// as a single line of JSON for status bar widgets
+	if args.Widget {
+		data, err := keybinds.WidgetJSON(config)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+		fmt.Fprintf(out, "%s\n", data)
+	}
+
+	// If --html is passed as an argument, print the keybinds
+	// as a standalone HTML page
+	if args.HTML {
+		page, err := keybinds.HTML(config, tableOpts)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+		fmt.Fprint(out, page)
+	}
+
+	// If --stats is passed as an argument, print a summary of the binds
+	if args.Stats {
+		stats := keybinds.NewStats(config)
+		if args.JSON {
+			data, err := json.MarshalIndent(stats, "", "  ")
 			if err != nil {
-				fmt.Println(err)
+				fmt.Fprintln(os.Stderr, "Error:", err)
 			}
-			fmt.Printf("%s\n", data)
-			save := parser.BuildConf(config)
-			err = ioutil.WriteFile("test/hyprland-generated.conf", []byte(save), 0644)
-			if err != nil {
-				panic(err)
+			fmt.Fprintf(out, "%s\n", data)
+		} else if err := keybinds.WriteStats(out, stats); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+	}
+
+	// If --conflicts is passed as an argument, report combinations bound more than once
+	// and exit with a non-zero status if there are any, so it can be used in CI
+	if args.Conflicts {
+		// Variables have to be resolved to compare $mainMod with SUPER
+		resolved := make([]keybinds.Keybind, len(config.Keybinds))
+		copy(resolved, config.Keybinds)
+		keybinds.SubstituteVariables(resolved, variables)
+
+		// Binds are grouped by their normalized combination, but shown as the first of them is written
+		conflicts := keybinds.FindConflicts(resolved)
+		for _, group := range conflicts {
+			fmt.Fprintf(out, "%s is bound %d times:\n", group[0].KeysInSubmap(), len(group))
+			for _, kb := range group {
+				fmt.Fprintf(out, "  %s: %s\n", kb.Location(), strings.TrimSpace(kb.Line))
 			}
 		}
+		if len(conflicts) > 0 {
+			return exitProblems
+		}
+	}
+
+	// If --flat is passed as an argument, print the config as it is read,
+	// sourced files included
+	if args.Flat {
+		if configPath == "-" {
+			fmt.Fprintln(os.Stderr, "Error: --flat needs a config file, a config read from stdin is already flat")
+			return exitUsage
+		}
+		flat, err := keybinds.Flatten(configPath, !args.RawVars)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return exitConfig
+		}
+		fmt.Fprint(out, flat)
+	}
+
+	if args.Blocks {
+		file, err := readConfigContent(configPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return exitConfig
+		}
+		content := string(file)
+		conf, errs := parser.Parse(content)
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", configPath, err)
+		}
+		doc, err := parser.NewDocument(conf)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return exitConfig
+		}
+		data, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+		fmt.Fprintf(out, "%s\n", data)
+		save, err := parser.BuildConf(conf)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error: could not regenerate the config:", err)
+			return exitConfig
+		}
+		err = ioutil.WriteFile(blocksOutputPath(args, configPath), []byte(save), 0644)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error: could not write the regenerated config:", err)
+			return exitConfig
+		}
 	}
+	return exitOK
 }
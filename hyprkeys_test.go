@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	flags "notashelf.dev/hyprkeys/util/cli"
+	"notashelf.dev/hyprkeys/util/keybinds"
+)
+
+func TestConfigPathsFromFlags(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags flags.Flags
+		env   map[string]string
+		want  []string
+	}{
+		{"--config wins over the environment", flags.Flags{Config: "/a.conf"}, map[string]string{"HYPRKEYS_CONFIG": "/env.conf"}, []string{"/a.conf"}},
+		{"--config and arguments", flags.Flags{Config: "/a.conf", Args: []string{"/b.conf"}}, nil, []string{"/a.conf", "/b.conf"}},
+		{"HYPRKEYS_CONFIG before HYPRLAND_CONFIG", flags.Flags{}, map[string]string{"HYPRKEYS_CONFIG": "/keys.conf", "HYPRLAND_CONFIG": "/land.conf"}, []string{"/keys.conf"}},
+		{"HYPRLAND_CONFIG", flags.Flags{}, map[string]string{"HYPRLAND_CONFIG": "/land.conf"}, []string{"/land.conf"}},
+		{"the defaults file before the environment", flags.Flags{DefaultConfig: "/defaults.conf"}, map[string]string{"HYPRLAND_CONFIG": "/land.conf"}, []string{"/defaults.conf"}},
+		{"the default path", flags.Flags{}, map[string]string{"XDG_CONFIG_HOME": "/xdg"}, []string{"/xdg/hypr/hyprland.conf"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"HYPRKEYS_CONFIG", "HYPRLAND_CONFIG", "XDG_CONFIG_HOME"} {
+				t.Setenv(name, tt.env[name])
+			}
+			if got := configPathsFromFlags(&tt.flags); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Call f and return what it wrote to stderr
+func captureStderr(t *testing.T, f func()) string {
+	t.Helper()
+	return capture(t, &os.Stderr, f)
+}
+
+// Call f and return what it wrote to file, os.Stdout or os.Stderr
+func capture(t *testing.T, file **os.File, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := *file
+	*file = w
+	defer func() { *file = saved }()
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+	f()
+	w.Close()
+	return <-done
+}
+
+func TestRunDiagnosticsGoToStderr(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"plain", nil},
+		{"markdown", []string{"--markdown"}},
+		{"json", []string{"--json"}},
+		{"keys only", []string{"--keys-only"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := flags.ReadFlags(nil, append(tt.args, "--color=never"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// run changes the binds it is given
+			config, err := keybinds.Parse([]byte("$a = $b\n$b = $a\nbind = SUPER, Q, exec, kitty\nbind = SUPER, W\nbind = $undefined, E, exec, x\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			stderr := captureStderr(t, func() { run(&out, args, "test.conf", config) })
+			if strings.Contains(out.String(), "Warning") {
+				t.Errorf("warnings in the output:\n%s", out.String())
+			}
+			for _, want := range []string{"skipping bind", "cycle", "$undefined"} {
+				if !strings.Contains(stderr, want) {
+					t.Errorf("no warning about %s on stderr:\n%s", want, stderr)
+				}
+			}
+		})
+	}
+}
+
+func TestRunFilters(t *testing.T) {
+	content := "$mod = SUPER\nbind = $mod, Q, killactive\nbind = $mod SHIFT, E, exec, kitty\nbind = ALT, T, exec, foot\nbind = ALT, F, fullscreen\n"
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--exclude-mod", "SUPER"}, "ALT + T\nALT + F\n"},
+		{[]string{"--filter-mod", "SUPER", "--exclude-dispatcher", "exec"}, "SUPER + Q\n"},
+		{[]string{"--filter-dispatcher", "exec", "--exclude-mod", "SHIFT"}, "ALT + T\n"},
+		{[]string{"--exclude-mod", "SUPER,ALT"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			args, err := flags.ReadFlags(nil, append(tt.args, "--keys-only", "--color=never"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			config, err := keybinds.Parse([]byte(content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			captureStderr(t, func() { run(&out, args, "test.conf", config) })
+			if out.String() != tt.want {
+				t.Errorf("got %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRunStrict(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    int
+		out     string
+	}{
+		{"apostrophe in the command", "bind = SUPER, N, exec, notify-send Don't\n", exitOK, "SUPER + N\n"},
+		{"apostrophe in a description", "bindd = SUPER, N, Don't close, killactive,\n", exitOK, "SUPER + N\n"},
+		{"quotes in the command", "bind = SUPER, E, exec, sh -c \"echo 'a, b'\"\n", exitOK, "SUPER + E\n"},
+		{"unbalanced quoted description", "bindd = SUPER, N, \"Close, killactive,\n", exitProblems, ""},
+		{"missing fields", "bind = SUPER, N\n", exitProblems, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := flags.ReadFlags(nil, []string{"--strict", "--keys-only", "--color=never"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			config, err := keybinds.Parse([]byte(tt.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			var status int
+			stderr := captureStderr(t, func() { status = run(&out, args, "test.conf", config) })
+			if status != tt.want {
+				t.Errorf("exit status %d, want %d, stderr:\n%s", status, tt.want, stderr)
+			}
+			if out.String() != tt.out {
+				t.Errorf("got %q, want %q", out.String(), tt.out)
+			}
+		})
+	}
+}
+
+func TestRunValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    int
+		out     string
+		stderr  string
+	}{
+		{"no problems", "$mod = SUPER\nbind = $mod, Q, killactive\n", exitOK, "", ""},
+		{"one problem", "bind = $mod, Q, killactive\n", exitProblems, "line 1: undefined variable $mod\n", "1 problem found\n"},
+		{"variable cycle", "bind = SUPER, Q, killactive\n$a = $b\n$b = $a\n", exitProblems, "line 3: variable $b references $a in a cycle, leaving it unresolved\n", "1 problem found\n"},
+		{"two problems", "$a = $a\nbind = $mod, Q, killactive\n", exitProblems, "line 1: variable $a references itself, leaving it unresolved\nline 2: undefined variable $mod\n", "2 problems found\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "hyprland.conf")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			args, err := flags.ReadFlags(nil, []string{"validate", path})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var status int
+			var out string
+			stderr := captureStderr(t, func() {
+				out = capture(t, &os.Stdout, func() { status = runValidate(args) })
+			})
+			if status != tt.want {
+				t.Errorf("exit status %d, want %d", status, tt.want)
+			}
+			if out = strings.ReplaceAll(out, path+":", "line "); out != tt.out {
+				t.Errorf("got %q, want %q", out, tt.out)
+			}
+			if stderr != tt.stderr {
+				t.Errorf("got %q on stderr, want %q", stderr, tt.stderr)
+			}
+		})
+	}
+}
+
+func TestRunConflicts(t *testing.T) {
+	args, err := flags.ReadFlags(nil, []string{"--conflicts", "--color=never"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := keybinds.Parse([]byte("$mod = SUPER\nbind = $mod SHIFT, Q, killactive\nbind = shift super, q, exit\nsubmap = resize\nbind = , Escape, submap, reset\nbind = , escape, exit\nsubmap = reset\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	var status int
+	captureStderr(t, func() { status = run(&out, args, "test.conf", config) })
+	if status != exitProblems {
+		t.Errorf("exit status %d, want %d", status, exitProblems)
+	}
+	for _, want := range []string{"SUPER SHIFT + Q is bound 2 times:\n", "Escape (submap resize) is bound 2 times:\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("%q not in:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package flags
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Shells WriteCompletion can write a script for
+var CompletionShells = []string{"bash", "zsh", "fish"}
+
+// Subcommands of hyprkeys, the first argument, and what they do
+var subcommands = []struct{ name, usage string }{
+	{"completion", "Print a shell completion script"},
+	{"schema", "Print a JSON Schema of the --json output"},
+	{"snapshot", "Save the binds of the config, for --since to compare with"},
+	{"validate", "Report the problems of the config"},
+}
+
+// Return the names of the subcommands
+func subcommandNames() []string {
+	var names []string
+	for _, sub := range subcommands {
+		names = append(names, sub.name)
+	}
+	return names
+}
+
+// Write a script completing the options of hyprkeys for shell, one of CompletionShells
+// Options taking a FILE complete file names, options with choices complete those
+func WriteCompletion(w io.Writer, shell string) error {
+	opts := (&Flags{}).options()
+	switch shell {
+	case "bash":
+		writeBashCompletion(w, opts)
+	case "zsh":
+		writeZshCompletion(w, opts)
+	case "fish":
+		writeFishCompletion(w, opts)
+	default:
+		return fmt.Errorf("unknown shell %q, expected one of %s", shell, strings.Join(CompletionShells, ", "))
+	}
+	return nil
+}
+
+// Return the names the option can be passed as, with their dashes
+func (opt option) names() []string {
+	names := []string{"--" + opt.long}
+	if opt.short != "" {
+		names = append(names, "-"+opt.short)
+	}
+	return names
+}
+
+func writeBashCompletion(w io.Writer, opts []option) {
+	var words []string
+	fmt.Fprintln(w, "_hyprkeys() {")
+	fmt.Fprintln(w, `	local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"`)
+	fmt.Fprintln(w, `	case "$prev" in`)
+	fmt.Fprintf(w, "\tcompletion) COMPREPLY=($(compgen -W %q -- \"$cur\")); return ;;\n", strings.Join(CompletionShells, " "))
+	for _, opt := range opts {
+		words = append(words, opt.names()...)
+		switch {
+		case opt.value == "FILE":
+			fmt.Fprintf(w, "\t%s) COMPREPLY=($(compgen -f -- \"$cur\")); return ;;\n", strings.Join(opt.names(), "|"))
+		case len(opt.choices) > 0:
+			fmt.Fprintf(w, "\t%s) COMPREPLY=($(compgen -W %q -- \"$cur\")); return ;;\n", strings.Join(opt.names(), "|"), strings.Join(opt.choices, " "))
+		case opt.value != "":
+			fmt.Fprintf(w, "\t%s) return ;;\n", strings.Join(opt.names(), "|"))
+		}
+	}
+	fmt.Fprintln(w, "\tesac")
+	fmt.Fprintf(w, "\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(append(words, subcommandNames()...), " "))
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "complete -o default -F _hyprkeys hyprkeys")
+}
+
+func writeZshCompletion(w io.Writer, opts []option) {
+	// Descriptions go in single quotes and brackets, so both need escaping
+	escape := strings.NewReplacer("'", `'\''`, "[", `\[`, "]", `\]`)
+	fmt.Fprintln(w, "#compdef hyprkeys")
+	fmt.Fprintln(w, "_arguments \\")
+	for _, opt := range opts {
+		spec := "'--" + opt.long
+		if opt.short != "" {
+			spec = "'(-" + opt.short + " --" + opt.long + ")'{-" + opt.short + ",--" + opt.long + "}'"
+		}
+		spec += "[" + escape.Replace(opt.usage) + "]"
+		switch {
+		case opt.value == "FILE":
+			spec += ":" + opt.value + ":_files"
+		case len(opt.choices) > 0:
+			spec += ":" + opt.value + ":(" + strings.Join(opt.choices, " ") + ")"
+		case opt.value != "":
+			spec += ":" + opt.value + ":"
+		}
+		fmt.Fprintf(w, "  %s' \\\n", spec)
+	}
+	fmt.Fprintf(w, "  '1::command:(%s)' \\\n", strings.Join(subcommandNames(), " "))
+	fmt.Fprintf(w, "  '2::shell:(%s)'\n", strings.Join(CompletionShells, " "))
+}
+
+func writeFishCompletion(w io.Writer, opts []option) {
+	escape := strings.NewReplacer(`\`, `\\`, "'", `\'`)
+	for _, sub := range subcommands {
+		fmt.Fprintf(w, "complete -c hyprkeys -n __fish_use_subcommand -a %s -d '%s'\n", sub.name, escape.Replace(sub.usage))
+	}
+	fmt.Fprintf(w, "complete -c hyprkeys -n '__fish_seen_subcommand_from completion' -x -a '%s'\n", strings.Join(CompletionShells, " "))
+	for _, opt := range opts {
+		line := "complete -c hyprkeys"
+		if opt.short != "" {
+			line += " -s " + opt.short
+		}
+		line += " -l " + opt.long
+		switch {
+		case opt.value == "FILE":
+			line += " -r -F"
+		case len(opt.choices) > 0:
+			line += " -x -a '" + strings.Join(opt.choices, " ") + "'"
+		case opt.value != "":
+			line += " -x"
+		}
+		fmt.Fprintf(w, "%s -d '%s'\n", line, escape.Replace(opt.usage))
+	}
+}
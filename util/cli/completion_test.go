@@ -0,0 +1,100 @@
+package flags
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Write the completion script for shell into a temporary file and return its path
+func completionScript(t *testing.T, shell string) string {
+	t.Helper()
+	var script bytes.Buffer
+	if err := WriteCompletion(&script, shell); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "hyprkeys."+shell)
+	if err := os.WriteFile(path, script.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCompletionScriptsSource(t *testing.T) {
+	// commands checking the syntax of a script without running it
+	checks := map[string][]string{
+		"bash": {"bash", "-n"},
+		"zsh":  {"zsh", "-n"},
+		"fish": {"fish", "--no-execute"},
+	}
+	for _, shell := range CompletionShells {
+		t.Run(shell, func(t *testing.T) {
+			check := checks[shell]
+			if _, err := exec.LookPath(check[0]); err != nil {
+				t.Skipf("%s isn't installed", check[0])
+			}
+			path := completionScript(t, shell)
+			if out, err := exec.Command(check[0], append(check[1:], path)...).CombinedOutput(); err != nil {
+				t.Errorf("%s: %v\n%s", strings.Join(check, " "), err, out)
+			}
+		})
+	}
+}
+
+func TestBashCompletion(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash isn't installed")
+	}
+	path := completionScript(t, "bash")
+	tests := []struct {
+		words []string // the last word is the one completed
+		want  string
+	}{
+		{[]string{"hyprkeys", "--mark-"}, "--mark-repeats"},
+		{[]string{"hyprkeys", "--sort", "di"}, "dispatcher"},
+		{[]string{"hyprkeys", "completion", "fi"}, "fish"},
+		{[]string{"hyprkeys", "--format", "mark"}, "markdown"},
+		{[]string{"hyprkeys", "val"}, "validate"},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.words, " "), func(t *testing.T) {
+			script := "source " + path + "\nCOMP_WORDS=(" + strings.Join(tt.words, " ") + ")\nCOMP_CWORD=" +
+				strconv.Itoa(len(tt.words)-1) + "\n_hyprkeys\necho \"${COMPREPLY[*]}\"\n"
+			out, err := exec.Command("bash", "-c", script).CombinedOutput()
+			if err != nil {
+				t.Fatalf("%v\n%s", err, out)
+			}
+			if got := strings.TrimSpace(string(out)); got != tt.want {
+				t.Errorf("completed %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompletionOptions(t *testing.T) {
+	for _, shell := range CompletionShells {
+		t.Run(shell, func(t *testing.T) {
+			var script bytes.Buffer
+			if err := WriteCompletion(&script, shell); err != nil {
+				t.Fatal(err)
+			}
+			for _, opt := range (&Flags{}).options() {
+				if !strings.Contains(script.String(), opt.long) {
+					t.Errorf("--%s isn't completed", opt.long)
+				}
+			}
+			for _, name := range subcommandNames() {
+				if !strings.Contains(script.String(), name) {
+					t.Errorf("subcommand %s isn't completed", name)
+				}
+			}
+		})
+	}
+	if err := WriteCompletion(&bytes.Buffer{}, "tcsh"); err == nil {
+		t.Error("WriteCompletion of an unknown shell returned no error")
+	}
+}
@@ -0,0 +1,52 @@
+package flags
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Read the options written in the file at path and return them as command line arguments
+// Each line is an option without its dashes, `name = value` for options taking a value
+// or just `name` for switches, e.g.
+//
+//	markdown
+//	color = always
+//
+// Lines starting with # are comments. A missing file has no options
+func ReadDefaults(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	known := make(map[string]bool)
+	for _, opt := range (&Flags{}).options() {
+		known[opt.long] = true
+	}
+
+	var args []string
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		name := strings.TrimLeft(strings.TrimSpace(parts[0]), "-")
+		if !known[name] {
+			return nil, fmt.Errorf("%s:%d: unknown option %q", path, lineNumber, name)
+		}
+		if len(parts) == 1 {
+			args = append(args, "--"+name)
+		} else {
+			args = append(args, "--"+name+"="+strings.TrimSpace(parts[1]))
+		}
+	}
+	return args, scanner.Err()
+}
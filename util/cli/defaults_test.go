@@ -0,0 +1,78 @@
+package flags
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadDefaults(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+		wantErr bool
+	}{
+		{"switches and values", "markdown\n# a comment\n\ncolor = always\n--sort=key\n", []string{"--markdown", "--color=always", "--sort=key"}, false},
+		{"unknown option", "markdwn\n", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadDefaults(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadDefaults() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadDefaults() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if args, err := ReadDefaults(filepath.Join(t.TempDir(), "missing")); args != nil || err != nil {
+		t.Errorf("ReadDefaults() of a missing file = %q, %v, want no options", args, err)
+	}
+}
+
+func TestReadFlagsDefaults(t *testing.T) {
+	tests := []struct {
+		name     string
+		defaults []string
+		args     []string
+		check    func(f *Flags) bool
+	}{
+		{"lists are replaced", []string{"--filter-mod=SUPER"}, []string{"--filter-mod", "ALT"},
+			func(f *Flags) bool { return reflect.DeepEqual(f.FilterMods, []string{"ALT"}) }},
+		{"lists passed twice add up", []string{"--filter-mod=SUPER"}, []string{"--filter-mod", "ALT", "--filter-mod", "SHIFT"},
+			func(f *Flags) bool { return reflect.DeepEqual(f.FilterMods, []string{"ALT", "SHIFT"}) }},
+		{"lists are kept", []string{"--filter-mod=SUPER"}, []string{"--json"},
+			func(f *Flags) bool { return reflect.DeepEqual(f.FilterMods, []string{"SUPER"}) }},
+		{"modes are replaced", []string{"--markdown"}, []string{"--json"},
+			func(f *Flags) bool { return f.JSON && !f.Markdown }},
+		{"modes are kept", []string{"--markdown"}, []string{"--sort", "key"},
+			func(f *Flags) bool { return f.Markdown && f.Sort == "key" }},
+		{"values are replaced", []string{"--color=always"}, []string{"--color", "never"},
+			func(f *Flags) bool { return f.Color == "never" }},
+		{"config from the defaults", []string{"--config=a.conf"}, []string{"b.conf"},
+			func(f *Flags) bool {
+				return f.Config == "" && f.DefaultConfig == "a.conf" && reflect.DeepEqual(f.Args, []string{"b.conf"})
+			}},
+		{"config on the command line", []string{"--config=a.conf"}, []string{"-c", "c.conf"},
+			func(f *Flags) bool { return f.Config == "c.conf" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ReadFlags(tt.defaults, tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.check(f) {
+				t.Errorf("ReadFlags(%q, %q) = %+v", tt.defaults, tt.args, *f)
+			}
+		})
+	}
+}
@@ -1,8 +1,375 @@
 package flags
 
-// TODO
-// If any flags are passed, store all of them in a map
-// We will then check this variable to see which flags
-// were passed, then maybe prevent some flags from being
-// passed together
-// TODO
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"notashelf.dev/hyprkeys/util/keybinds"
+)
+
+// Options passed on the command line
+// Every option has a long form, the common ones also have a short form
+type Flags struct {
+	Help        bool
+	Version     bool
+	Test        bool
+	Config      string
+	Output      string // file the output is written to instead of stdout
+	BlocksOut   string // file --blocks writes the regenerated config to
+	Markdown    bool
+	JSON        bool
+	YAML        bool
+	HTML        bool
+	CSV         bool
+	Rofi        bool
+	Man         bool
+	Widget      bool     // compact JSON for eww and waybar widgets
+	KeysOnly    bool     // only the key combinations, one per line
+	Formats     []string // several of FormatNames to write in one run, each to its own file
+	OutputDir   string   // directory the Formats are written to, as keybinds.EXT
+	Verbose     bool
+	Variables   bool // variables are resolved by default, on its own it still prints the markdown table for existing scripts
+	RawVars     bool
+	ResolveExec bool // expand the commands of exec binds to what they run
+	Comments    bool
+	MarkRepeats bool // label the keys of binde= binds with (repeats)
+	NoDedup     bool
+	NoHeader    bool
+	ShowFlags   bool
+	Pretty      bool
+	Separator   string   // between the modifiers and the key, empty for the default " + "
+	Symbols     []string // NAME=SYMBOL overrides of the symbols --pretty uses
+	Strict      bool     // fail on bind lines that can't be parsed instead of skipping them
+
+	// Only binds using all of these modifiers are shown
+	FilterMods []string
+	// Only binds calling one of these dispatchers are shown
+	FilterDispatchers []string
+	// Binds using any of these modifiers are left out, after the filters above
+	ExcludeMods []string
+	// Binds calling one of these dispatchers are left out, after the filters above
+	ExcludeDispatchers []string
+	// Only binds written in the device section of one of these devices are shown
+	FilterDevices []string
+	// Only binds whose dispatcher or command contains this are shown
+	Grep        string
+	GrepRegex   bool     // treat Grep as a regular expression
+	Sort        string   // key, mod or dispatcher, empty to keep the order of the config
+	Columns     []string // columns of the markdown, CSV and HTML tables
+	Blocks      bool
+	Flat        bool // print the config with the files it sources inlined
+	Conflicts   bool
+	Stats       bool
+	Count       bool
+	OnlyUnbound bool     // print the combinations nothing is bound to
+	Rules       bool     // print the window rules instead of the binds
+	UnboundMods []string // modifier sets --only-unbound combines with UnboundKeys
+	UnboundKeys []string // keys --only-unbound checks, ranges like a-z included
+	Watch       bool
+	TUI         bool   // browse the binds interactively
+	Diff        bool   // compare the two configs given as arguments
+	Since       bool   // compare the config with the snapshot saved by the snapshot subcommand
+	Width       int    // width the plain table is fit into, -1 unless --width is passed
+	Color       string // when to color the plain table: auto, always or never
+	KbdStyle    string // how the markdown table wraps keys, see keybinds.KbdStyles
+	GroupBy     string // submap, dispatcher or mod to split the markdown table into sections, empty for one table
+
+	// Positional arguments, a lone "-" reads the config from stdin
+	Args []string
+	// Config set in the defaults file, read when neither -c/--config nor configs are passed as arguments
+	DefaultConfig string
+}
+
+// A single command line option
+// Both the flag parser and the help text are built from these, so the help
+// can't advertise an option that isn't handled
+type option struct {
+	short string // single letter form, empty if the option has none
+	long  string
+	value string // name of the value the option takes, empty for switches
+	usage string
+	// values the option accepts, offered by shell completion
+	choices []string
+
+	boolVal   *bool
+	stringVal *string
+	intVal    *int
+	listVal   *[]string
+}
+
+// Value of an option that may be passed several times, each time with a comma separated list
+// The first time it is passed it replaces the list it already has, the ones after that add to it
+type listValue struct {
+	list *[]string
+	set  bool
+}
+
+func (l *listValue) String() string {
+	if l == nil || l.list == nil {
+		return ""
+	}
+	return strings.Join(*l.list, ",")
+}
+
+func (l *listValue) Set(value string) error {
+	if !l.set {
+		*l.list = nil
+		l.set = true
+	}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l.list = append(*l.list, item)
+		}
+	}
+	return nil
+}
+
+// Return every option, bound to the fields of f
+func (f *Flags) options() []option {
+	return []option{
+		{short: "h", long: "help", usage: "Show this help message", boolVal: &f.Help},
+		{short: "c", long: "config", value: "FILE", usage: "Read the configuration from FILE, before the CONFIGs passed as arguments", stringVal: &f.Config},
+		{short: "t", long: "test", usage: "Use the test configuration file", boolVal: &f.Test},
+		{long: "blocks", usage: "Print the config sections as JSON and regenerate the config", boolVal: &f.Blocks},
+		{long: "flat", usage: "Print the config with the files it sources written in place, and its variables resolved unless --raw-vars is passed", boolVal: &f.Flat},
+		{long: "blocks-output", value: "FILE", usage: "Where --blocks writes the regenerated config", stringVal: &f.BlocksOut},
+		{short: "o", long: "output", value: "FILE", usage: "Write the output to FILE instead of stdout", stringVal: &f.Output},
+		{short: "m", long: "markdown", usage: "Print the binds as a markdown table", boolVal: &f.Markdown},
+		{long: "json", usage: "Print the binds as JSON", boolVal: &f.JSON},
+		{long: "yaml", usage: "Print the binds as YAML", boolVal: &f.YAML},
+		{long: "html", usage: "Print the binds as a standalone HTML page", boolVal: &f.HTML},
+		{long: "csv", usage: "Print the binds as CSV", boolVal: &f.CSV},
+		{long: "rofi", usage: "Print the binds as tab separated lines for rofi -dmenu or wofi --dmenu", boolVal: &f.Rofi},
+		{long: "man", usage: "Print the binds as a roff man page, for man -l", boolVal: &f.Man},
+		{long: "keys-only", usage: "Print just the key combinations bound, one per line and each once, in the order of --sort", boolVal: &f.KeysOnly},
+		{long: "widget-json", usage: "Print the binds as compact JSON grouped by submap or modifier, for eww and waybar", boolVal: &f.Widget},
+		{long: "format", value: "LIST", usage: "Write each of the comma separated formats to its own file, into --output-dir or the comma separated --output files", choices: FormatNames, listVal: &f.Formats},
+		{long: "output-dir", value: "DIR", usage: "Directory --format writes keybinds.md, keybinds.json and so on to", stringVal: &f.OutputDir},
+		{long: "filter-mod", value: "MODS", usage: "Only show binds using all of the comma separated modifiers", listVal: &f.FilterMods},
+		{long: "filter-dispatcher", value: "NAMES", usage: "Only show binds calling one of the comma separated dispatchers", listVal: &f.FilterDispatchers},
+		{long: "exclude-mod", value: "MODS", usage: "Leave out binds using any of the comma separated modifiers", listVal: &f.ExcludeMods},
+		{long: "exclude-dispatcher", value: "NAMES", usage: "Leave out binds calling one of the comma separated dispatchers", listVal: &f.ExcludeDispatchers},
+		{long: "filter-device", value: "NAMES", usage: "Only show binds written in the device section of one of the comma separated devices", listVal: &f.FilterDevices},
+		{long: "grep", value: "PATTERN", usage: "Only show binds whose dispatcher or command contains PATTERN", stringVal: &f.Grep},
+		{long: "grep-regex", usage: "Treat the --grep pattern as a regular expression", boolVal: &f.GrepRegex},
+		{long: "sort", value: "BY", usage: "Sort the binds by key, mod or dispatcher instead of the order of the config", choices: []string{"key", "mod", "dispatcher"}, stringVal: &f.Sort},
+		{long: "columns", value: "NAMES", usage: "Comma separated columns of the markdown, CSV and HTML tables, e.g. mod,key,dispatcher,command,flags", choices: keybinds.ColumnNames, listVal: &f.Columns},
+		{long: "width", value: "N", usage: "Fit the plain table into N columns instead of the terminal width, 0 never truncates", intVal: &f.Width},
+		{long: "color", value: "WHEN", usage: "Color the plain table: auto (when printing to a terminal), always (e.g. piped into less -R) or never", choices: []string{"auto", "always", "never"}, stringVal: &f.Color},
+		{long: "kbd-style", value: "STYLE", usage: "Wrap the keys of the markdown table in kbd tags (the default), a code span, bold or nothing", choices: keybinds.KbdStyles, stringVal: &f.KbdStyle},
+		{long: "group-by", value: "BY", usage: "Split the markdown table into a section per submap, dispatcher or mod, each under a heading", choices: keybinds.GroupByNames, stringVal: &f.GroupBy},
+		{long: "show-flags", usage: "Add a column naming the flags of binds, like locked for bindl=, to the markdown and HTML tables", boolVal: &f.ShowFlags},
+		{long: "no-header", usage: "Leave out the header of the markdown, CSV and plain tables", boolVal: &f.NoHeader},
+		{long: "separator", value: "SEP", usage: "Separate the modifiers from the key with SEP instead of \" + \", e.g. - for SUPER-Q", stringVal: &f.Separator},
+		{long: "pretty", usage: "Show modifiers as symbols, e.g. ⌘ for SUPER and ⇧ for SHIFT", boolVal: &f.Pretty},
+		{long: "symbols", value: "LIST", usage: "Comma separated NAME=SYMBOL overrides of the --pretty symbols, e.g. SUPER=⊞", listVal: &f.Symbols},
+		{long: "variables", usage: "Replace variables in the binds with their values, this is the default. On its own, print the markdown table", boolVal: &f.Variables},
+		{long: "raw-vars", usage: "Keep variables like $mainMod in the binds instead of their values", boolVal: &f.RawVars},
+		{long: "resolve-exec", usage: "Show the commands of exec binds as they run, with variables, ~ and environment variables like $HOME expanded", boolVal: &f.ResolveExec},
+		{long: "no-dedup", usage: "Keep binds that repeat an earlier bind exactly", boolVal: &f.NoDedup},
+		{long: "comments", usage: "Show the comments written above or after binds as their description", boolVal: &f.Comments},
+		{long: "mark-repeats", usage: "Label the keys of binds that repeat while held, declared with binde=, with (repeats) in the plain and markdown tables", boolVal: &f.MarkRepeats},
+		{long: "stats", usage: "Print how many binds use each modifier and dispatcher, as JSON with --json", boolVal: &f.Stats},
+		{long: "strict", usage: "Fail on bind lines that can't be parsed instead of skipping them with a warning", boolVal: &f.Strict},
+		{long: "count", usage: "Print how many binds there are, after any filters, and nothing else", boolVal: &f.Count},
+		{long: "rules", usage: "Print the windowrule and windowrulev2 lines of the config instead of the binds, as markdown with --markdown", boolVal: &f.Rules},
+		{long: "only-unbound", usage: "Print the combinations of --unbound-mods and --unbound-keys that nothing is bound to (experimental)", boolVal: &f.OnlyUnbound},
+		{long: "unbound-mods", value: "MODS", usage: "Comma separated modifier sets --only-unbound checks, e.g. SUPER,SUPER SHIFT, SUPER by default", listVal: &f.UnboundMods},
+		{long: "unbound-keys", value: "KEYS", usage: "Comma separated keys --only-unbound checks, ranges like a-z and F1-F12 included, A-Z,0-9 by default", listVal: &f.UnboundKeys},
+		{long: "conflicts", usage: "Report key combinations that are bound more than once", boolVal: &f.Conflicts},
+		{long: "diff", usage: "Compare the binds of the configs OLD and NEW, as JSON with --json", boolVal: &f.Diff},
+		{long: "since", usage: "Compare the binds with the snapshot saved by `hyprkeys snapshot`, as JSON with --json", boolVal: &f.Since},
+		{long: "tui", usage: "Browse and search the binds interactively, the plain table is printed when not in a terminal", boolVal: &f.TUI},
+		{long: "watch", usage: "Print the binds again whenever the config or a file it sources changes", boolVal: &f.Watch},
+		{short: "v", long: "verbose", usage: "Print text as is, without making it pretty", boolVal: &f.Verbose},
+		{short: "V", long: "version", usage: "Show the version number", boolVal: &f.Version},
+	}
+}
+
+// Return true if an option selecting what to print was passed
+// Without one the binds are printed as a plain table
+func (f *Flags) ModeSelected() bool {
+	for _, mode := range f.modes() {
+		if *mode {
+			return true
+		}
+	}
+	return false
+}
+
+// Return the options selecting what to print, see ModeSelected
+func (f *Flags) modes() []*bool {
+	return []*bool{
+		&f.Markdown, &f.JSON, &f.YAML, &f.HTML, &f.CSV, &f.Rofi, &f.Man, &f.Widget, &f.KeysOnly,
+		&f.Verbose, &f.Blocks, &f.Flat, &f.Conflicts, &f.Stats, &f.Count, &f.OnlyUnbound, &f.Rules,
+	}
+}
+
+// Formats --format accepts, and the extension of the file each is written to in --output-dir
+var FormatNames = []string{"plain", "markdown", "json", "yaml", "html", "csv", "man"}
+
+var formatExtensions = map[string]string{
+	"plain": "txt", "markdown": "md", "json": "json", "yaml": "yaml", "html": "html", "csv": "csv", "man": "1",
+}
+
+// Return the file a format passed to --format is written to in dir
+func FormatFile(dir string, format string) string {
+	return filepath.Join(dir, "keybinds."+formatExtensions[format])
+}
+
+// Return a copy of f printing only format, one of FormatNames, to output
+// Every other option selecting what to print is turned off, and so is --tui
+func (f *Flags) ForFormat(format string, output string) *Flags {
+	single := *f
+	single.Formats = nil
+	single.Output = output
+	single.TUI = false
+	for _, mode := range single.modes() {
+		*mode = false
+	}
+	switch format {
+	case "markdown":
+		single.Markdown = true
+	case "json":
+		single.JSON = true
+	case "yaml":
+		single.YAML = true
+	case "html":
+		single.HTML = true
+	case "csv":
+		single.CSV = true
+	case "man":
+		single.Man = true
+	}
+	return &single
+}
+
+// Parse the command line arguments, without the program name, over the options read from the defaults file
+// Flags and positional arguments may be given in any order. Options on the command line replace the defaults:
+// a list passed on it replaces the list of the defaults, and an option selecting what to print replaces the
+// ones the defaults select. See ReadDefaults
+func ReadFlags(defaults []string, args []string) (*Flags, error) {
+	f := &Flags{Width: -1, Color: "auto", KbdStyle: "kbd"}
+	if err := f.parse(defaults); err != nil {
+		return nil, fmt.Errorf("in the defaults: %w", err)
+	}
+	// -c/--config on the command line and configs passed as arguments are read together, see DefaultConfig
+	f.DefaultConfig, f.Config = f.Config, ""
+	modes := f.modes()
+	defaultModes := make([]bool, len(modes))
+	for i, mode := range modes {
+		defaultModes[i], *mode = *mode, false
+	}
+
+	if err := f.parse(args); err != nil {
+		return nil, err
+	}
+	if !f.ModeSelected() {
+		for i, mode := range modes {
+			*mode = defaultModes[i]
+		}
+	}
+	// --variables used to print the markdown table, scripts passing nothing else still get it
+	if f.Variables && !f.ModeSelected() && !f.TUI && !f.Diff && !f.Since && len(f.Formats) == 0 {
+		f.Markdown = true
+	}
+
+	switch f.Sort {
+	case "", "key", "mod", "dispatcher":
+	default:
+		return nil, fmt.Errorf("invalid value %q for --sort, expected key, mod or dispatcher", f.Sort)
+	}
+
+	if err := keybinds.CheckColumns(f.Columns); err != nil {
+		return nil, fmt.Errorf("invalid value for --columns: %w", err)
+	}
+
+	switch f.Color {
+	case "auto", "always", "never":
+	default:
+		return nil, fmt.Errorf("invalid value %q for --color, expected auto, always or never", f.Color)
+	}
+
+	for _, format := range f.Formats {
+		if _, ok := formatExtensions[format]; !ok {
+			return nil, fmt.Errorf("invalid value %q for --format, expected one of %s", format, strings.Join(FormatNames, ", "))
+		}
+	}
+
+	switch f.GroupBy {
+	case "", "submap", "dispatcher", "mod":
+	default:
+		return nil, fmt.Errorf("invalid value %q for --group-by, expected one of %s", f.GroupBy, strings.Join(keybinds.GroupByNames, ", "))
+	}
+
+	switch f.KbdStyle {
+	case "kbd", "code", "bold", "plain":
+	default:
+		return nil, fmt.Errorf("invalid value %q for --kbd-style, expected one of %s", f.KbdStyle, strings.Join(keybinds.KbdStyles, ", "))
+	}
+
+	return f, nil
+}
+
+// Parse args into f, the options they don't pass are left as f has them
+func (f *Flags) parse(args []string) error {
+	fs := flag.NewFlagSet("hyprkeys", flag.ContinueOnError)
+	// Errors are returned to the caller, which decides how to report them
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {}
+
+	for _, opt := range f.options() {
+		names := []string{opt.long}
+		if opt.short != "" {
+			names = append(names, opt.short)
+		}
+		// shared by both names, so passing the long and the short form adds to the same list
+		list := &listValue{list: opt.listVal}
+		for _, name := range names {
+			switch {
+			case opt.boolVal != nil:
+				fs.BoolVar(opt.boolVal, name, *opt.boolVal, opt.usage)
+			case opt.intVal != nil:
+				fs.IntVar(opt.intVal, name, *opt.intVal, opt.usage)
+			case opt.listVal != nil:
+				fs.Var(list, name, opt.usage)
+			default:
+				fs.StringVar(opt.stringVal, name, *opt.stringVal, opt.usage)
+			}
+		}
+	}
+
+	// The flag package stops at the first positional argument,
+	// so keep parsing whatever follows it
+	for {
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return nil
+		}
+		f.Args = append(f.Args, args[0])
+		args = args[1:]
+	}
+}
+
+// Write the list of options, one per line, as shown in the help message
+func PrintOptions(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, opt := range (&Flags{}).options() {
+		name := "--" + opt.long
+		if opt.short != "" {
+			name = "-" + opt.short + ", " + name
+		}
+		if opt.value != "" {
+			name += " " + opt.value
+		}
+		fmt.Fprintf(tw, "  %s\t%s\n", name, opt.usage)
+	}
+	tw.Flush()
+}
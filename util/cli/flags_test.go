@@ -0,0 +1,31 @@
+package flags
+
+import "testing"
+
+func TestReadFlagsVariables(t *testing.T) {
+	tests := []struct {
+		name     string
+		defaults []string
+		args     []string
+		markdown bool
+	}{
+		{"on its own", nil, []string{"--variables"}, true},
+		{"with other options", nil, []string{"--variables", "--sort", "key", "hyprland.conf"}, true},
+		{"with an output format", nil, []string{"--variables", "--json"}, false},
+		{"with a mode of the defaults", []string{"--keys-only"}, []string{"--variables"}, false},
+		{"with --tui", nil, []string{"--variables", "--tui"}, false},
+		{"with --format", nil, []string{"--variables", "--format", "json"}, false},
+		{"without it", nil, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ReadFlags(tt.defaults, tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.Markdown != tt.markdown {
+				t.Errorf("ReadFlags(%q, %q) sets --markdown %v, want %v", tt.defaults, tt.args, f.Markdown, tt.markdown)
+			}
+		})
+	}
+}
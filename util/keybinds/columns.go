@@ -0,0 +1,50 @@
+package keybinds
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A column of the markdown, CSV and HTML tables, picked with --columns
+type column struct {
+	title string               // header of markdown and HTML tables
+	csv   string               // header of CSV tables
+	value func(Keybind) string // cell as shown in markdown and HTML tables
+	raw   func(Keybind) string // cell as written in CSV tables, nil if it is the same as value
+}
+
+// Names of the columns, in the order they are listed in errors
+var ColumnNames = []string{"keybind", "mod", "key", "dispatcher", "command", "flags", "submap", "device", "description", "file", "line"}
+
+var columns = map[string]column{
+	"keybind":     {title: "Keybind", csv: "keybind", value: Keybind.Keys},
+	"mod":         {title: "Modifiers", csv: "modifiers", value: func(kb Keybind) string { return kb.Mods }},
+	"key":         {title: "Key", csv: "key", value: Keybind.DisplayKey, raw: func(kb Keybind) string { return kb.Key }},
+	"dispatcher":  {title: "Dispatcher", csv: "dispatcher", value: func(kb Keybind) string { return kb.Dispatcher }},
+	"command":     {title: "Command", csv: "command", value: Keybind.DisplayCommand, raw: func(kb Keybind) string { return kb.Args }},
+	"flags":       {title: "Flags", csv: "flags", value: Keybind.FlagNames, raw: func(kb Keybind) string { return kb.Flags }},
+	"submap":      {title: "Submap", csv: "submap", value: func(kb Keybind) string { return kb.Submap }},
+	"device":      {title: "Device", csv: "device", value: func(kb Keybind) string { return kb.Device }},
+	"description": {title: "Description", csv: "description", value: func(kb Keybind) string { return kb.Description }},
+	"file":        {title: "File", csv: "file", value: func(kb Keybind) string { return kb.SourceFile }},
+	"line":        {title: "Line", csv: "line", value: func(kb Keybind) string { return strconv.Itoa(kb.LineNumber) }},
+}
+
+// Return an error naming the first of names that isn't a column, see ColumnNames
+func CheckColumns(names []string) error {
+	for _, name := range names {
+		if _, ok := columns[name]; !ok {
+			return fmt.Errorf("unknown column %q, expected one of %s", name, strings.Join(ColumnNames, ", "))
+		}
+	}
+	return nil
+}
+
+// Return the raw cell of a column, as written in CSV tables
+func (col column) rawValue(kb Keybind) string {
+	if col.raw != nil {
+		return col.raw(kb)
+	}
+	return col.value(kb)
+}
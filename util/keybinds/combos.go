@@ -0,0 +1,27 @@
+package keybinds
+
+import (
+	"fmt"
+	"io"
+)
+
+// Write the key combinations of the binds of c one per line, e.g. SUPER SHIFT + Q, in the order of the binds
+// A combination bound more than once, in several submaps say, is written once, compared like ComboKey compares them.
+// sep separates the modifiers from the key, the default separator if it's empty
+func WriteKeys(w io.Writer, c Config, sep string) error {
+	if sep == "" {
+		sep = DefaultSeparator
+	}
+	seen := make(map[string]bool)
+	for _, kb := range c.Keybinds {
+		combo := ComboKey(Keybind{Mods: kb.Mods, Key: kb.Key})
+		if seen[combo] {
+			continue
+		}
+		seen[combo] = true
+		if _, err := fmt.Fprintln(w, kb.KeysWith(sep)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package keybinds
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// Write the binds of c as CSV, with a header row unless opts.NoHeader is set
+// The fields are written as in the config, by default the modifiers, key, dispatcher, command and flags.
+// encoding/csv quotes fields containing commas or quotes, so commands survive intact
+func WriteCSV(w io.Writer, c Config, opts TableOptions) error {
+	names := opts.Columns
+	if len(names) == 0 {
+		names = []string{"mod", "key", "dispatcher", "command", "flags"}
+	}
+	cw := csv.NewWriter(w)
+	if !opts.NoHeader {
+		header := make([]string, len(names))
+		for i, name := range names {
+			header[i] = columns[name].csv
+		}
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+	}
+	for _, kb := range c.Keybinds {
+		record := make([]string, len(names))
+		for i, name := range names {
+			record[i] = columns[name].rawValue(kb)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,80 @@
+package keybinds
+
+import (
+	"fmt"
+	"io"
+)
+
+// Binds that differ between two configs, printed by --diff
+// The JSON field names make up the --diff --json output, keep them stable
+type Diff struct {
+	Added   []Keybind `json:"added"`
+	Removed []Keybind `json:"removed"`
+	Changed []Change  `json:"changed"`
+}
+
+// A combination bound in both configs, to a different action
+type Change struct {
+	Old Keybind `json:"old"`
+	New Keybind `json:"new"`
+}
+
+// Return true if the configs bind the same combinations to the same actions
+func (d Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Compare the binds of two configs by the combination they are triggered by, see ComboKey
+// Variables should be substituted and the binds normalized first, so $mainMod and SUPER compare equal.
+// A combination bound several times is matched up in the order the binds appear
+func Compare(before, after []Keybind) Diff {
+	diff := Diff{Added: []Keybind{}, Removed: []Keybind{}, Changed: []Change{}}
+
+	unmatched := make(map[string][]int)
+	for i, kb := range after {
+		combo := ComboKey(kb)
+		unmatched[combo] = append(unmatched[combo], i)
+	}
+
+	matched := make(map[int]bool)
+	for _, kb := range before {
+		combo := ComboKey(kb)
+		if len(unmatched[combo]) == 0 {
+			diff.Removed = append(diff.Removed, kb)
+			continue
+		}
+		i := unmatched[combo][0]
+		unmatched[combo] = unmatched[combo][1:]
+		matched[i] = true
+		if kb.Action() != after[i].Action() || kb.Flags != after[i].Flags {
+			diff.Changed = append(diff.Changed, Change{Old: kb, New: after[i]})
+		}
+	}
+	for i, kb := range after {
+		if !matched[i] {
+			diff.Added = append(diff.Added, kb)
+		}
+	}
+	return diff
+}
+
+// Write the diff for reading in a terminal, a line per bind like diff -u
+// Removed binds start with -, added ones with + and changed ones with ~
+func WriteDiff(w io.Writer, d Diff) error {
+	for _, kb := range d.Removed {
+		if _, err := fmt.Fprintf(w, "- %s  %s\n", kb.Keys(), kb.Action()); err != nil {
+			return err
+		}
+	}
+	for _, change := range d.Changed {
+		if _, err := fmt.Fprintf(w, "~ %s  %s -> %s\n", change.New.Keys(), change.Old.Action(), change.New.Action()); err != nil {
+			return err
+		}
+	}
+	for _, kb := range d.Added {
+		if _, err := fmt.Fprintf(w, "+ %s  %s\n", kb.Keys(), kb.Action()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package keybinds
+
+import (
+	"encoding/json"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Return the binds of c as an indented JSON array
+func JSON(c Config) ([]byte, error) {
+	return json.MarshalIndent(nonNil(c.Keybinds), "", "  ")
+}
+
+// Return the binds of c as a YAML list, in the order they appear in the config
+func YAML(c Config) ([]byte, error) {
+	return yaml.Marshal(nonNil(c.Keybinds))
+}
+
+// Return keybinds, or an empty slice if it is nil
+// A config without binds should encode as an empty list rather than null
+func nonNil(keybinds []Keybind) []Keybind {
+	if keybinds == nil {
+		return []Keybind{}
+	}
+	return keybinds
+}
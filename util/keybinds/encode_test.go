@@ -0,0 +1,65 @@
+package keybinds
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+// Return a writer for a format encoded all at once, like JSON
+func encoded(encode func(Config) ([]byte, error)) func(io.Writer, Config) error {
+	return func(w io.Writer, c Config) error {
+		out, err := encode(c)
+		w.Write(out)
+		return err
+	}
+}
+
+func TestEmptyConfig(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(io.Writer, Config) error
+		want  string // the output, or what it starts with when it ends in ...
+		json  bool
+	}{
+		{"json", encoded(JSON), "[]", true},
+		{"yaml", encoded(YAML), "[]\n", false},
+		{"widget json", encoded(WidgetJSON), `{"version":1,"groups":[]}`, true},
+		{"markdown", func(w io.Writer, c Config) error {
+			_, err := io.WriteString(w, strings.Join(Markdown(c, TableOptions{}), "\n"))
+			return err
+		}, "| Keybind | Dispatcher | Command |\n|---------|------------|---------|", false},
+		{"csv", func(w io.Writer, c Config) error { return WriteCSV(w, c, TableOptions{}) }, "modifiers,key,dispatcher,command,flags\n", false},
+		{"keys only", func(w io.Writer, c Config) error { return WriteKeys(w, c, "") }, "", false},
+		{"rofi", WriteRofi, "", false},
+		{"html", func(w io.Writer, c Config) error {
+			out, err := HTML(c, TableOptions{})
+			io.WriteString(w, out)
+			return err
+		}, "<!DOCTYPE html>...", false},
+	}
+	for _, content := range []string{"", "# only a comment\n$mod = SUPER\n"} {
+		c := mustParse(t, content)
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var out bytes.Buffer
+				if err := tt.write(&out, c); err != nil {
+					t.Fatal(err)
+				}
+				got := out.String()
+				if prefix := strings.TrimSuffix(tt.want, "..."); prefix != tt.want {
+					if !strings.HasPrefix(got, prefix) {
+						t.Errorf("got %q, want it to start with %q", got, prefix)
+					}
+				} else if got != tt.want {
+					t.Errorf("got %q, want %q", got, tt.want)
+				}
+				if tt.json && !json.Valid(out.Bytes()) {
+					t.Errorf("invalid JSON: %s", got)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package keybinds
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Return the binds that use every one of the given modifiers
+// Modifiers are compared by their canonical names, with the variables used by the binds resolved
+func FilterByMods(keybinds []Keybind, mods []string, variables map[string]string) []Keybind {
+	wanted := canonicalMods(mods)
+	replacer := variableReplacer(variables)
+	var filtered []Keybind
+	for _, kb := range keybinds {
+		have := bindMods(kb, replacer)
+		matches := true
+		for _, mod := range wanted {
+			if !have[mod] {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			filtered = append(filtered, kb)
+		}
+	}
+	return filtered
+}
+
+// Return the binds that use none of the given modifiers, compared like FilterByMods compares them
+func ExcludeMods(keybinds []Keybind, mods []string, variables map[string]string) []Keybind {
+	unwanted := canonicalMods(mods)
+	replacer := variableReplacer(variables)
+	var filtered []Keybind
+	for _, kb := range keybinds {
+		have := bindMods(kb, replacer)
+		matches := false
+		for _, mod := range unwanted {
+			if have[mod] {
+				matches = true
+				break
+			}
+		}
+		if !matches {
+			filtered = append(filtered, kb)
+		}
+	}
+	return filtered
+}
+
+// Return the canonical names of the modifiers, e.g. ["SUPER", "SHIFT"] for ["mod4 shift"]
+func canonicalMods(mods []string) []string {
+	var canonical []string
+	for _, mod := range mods {
+		canonical = append(canonical, strings.Fields(strings.ToUpper(NormalizeMods(mod)))...)
+	}
+	return canonical
+}
+
+// Return the canonical names of the modifiers a bind uses, with its variables substituted by variables
+// The replacer is built once by the caller, see variableReplacer, building it for every bind is what makes filtering slow
+func bindMods(kb Keybind, variables *strings.Replacer) map[string]bool {
+	have := make(map[string]bool)
+	for _, mod := range strings.Fields(strings.ToUpper(NormalizeMods(variables.Replace(kb.Mods)))) {
+		have[mod] = true
+	}
+	return have
+}
+
+// Return the binds calling one of the given dispatchers, compared case-insensitively
+func FilterByDispatchers(keybinds []Keybind, dispatchers []string) []Keybind {
+	var filtered []Keybind
+	for _, kb := range keybinds {
+		for _, dispatcher := range dispatchers {
+			if strings.EqualFold(kb.Dispatcher, dispatcher) {
+				filtered = append(filtered, kb)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// Return the binds calling none of the given dispatchers, compared case-insensitively
+func ExcludeDispatchers(keybinds []Keybind, dispatchers []string) []Keybind {
+	var filtered []Keybind
+	for _, kb := range keybinds {
+		if len(FilterByDispatchers([]Keybind{kb}, dispatchers)) == 0 {
+			filtered = append(filtered, kb)
+		}
+	}
+	return filtered
+}
+
+// Return the binds written in the device section of one of the given devices, compared case-insensitively
+func FilterByDevices(keybinds []Keybind, devices []string) []Keybind {
+	var filtered []Keybind
+	for _, kb := range keybinds {
+		for _, device := range devices {
+			if kb.Device != "" && strings.EqualFold(kb.Device, device) {
+				filtered = append(filtered, kb)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// Return the binds whose dispatcher or command matches
+func FilterByMatch(keybinds []Keybind, matches func(string) bool) []Keybind {
+	var filtered []Keybind
+	for _, kb := range keybinds {
+		if matches(kb.Dispatcher) || matches(kb.Args) {
+			filtered = append(filtered, kb)
+		}
+	}
+	return filtered
+}
+
+// Return the function --grep matches dispatchers and commands with
+// Plain patterns match as case-insensitive substrings, with --grep-regex they are regular expressions
+func GrepMatcher(pattern string, isRegex bool) (func(string) bool, error) {
+	if isRegex {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString, nil
+	}
+	pattern = strings.ToLower(pattern)
+	return func(s string) bool {
+		return strings.Contains(strings.ToLower(s), pattern)
+	}, nil
+}
+
+// Return the binds with repeated ones left out, keeping the first of them
+// Binds repeat when they bind the same combination with the same flags to the same action,
+// see FindConflicts for combinations bound to different actions
+func Dedup(keybinds []Keybind) []Keybind {
+	seen := make(map[string]bool)
+	var deduped []Keybind
+	for _, kb := range keybinds {
+		id := ComboKey(kb) + "\x00" + kb.Flags + "\x00" + kb.Action()
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		deduped = append(deduped, kb)
+	}
+	return deduped
+}
@@ -0,0 +1,93 @@
+package keybinds
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Return the keys of the binds, in order
+func bindKeys(keybinds []Keybind) []string {
+	var keys []string
+	for _, kb := range keybinds {
+		keys = append(keys, kb.Key)
+	}
+	return keys
+}
+
+func TestParseDeviceBinds(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		devices []string // the device of each bind, in order
+	}{
+		{"named section", "device:epic-mouse-v1 {\n    sensitivity = -0.5\n    bind = , mouse:275, exec, kitty\n}\nbind = SUPER, Q, killactive\n", []string{"epic-mouse-v1", ""}},
+		{"name option", "device {\n    name = at-translated-set-2-keyboard\n    bind = , F1, exec, kitty\n}\n", []string{"at-translated-set-2-keyboard"}},
+		{"spaces", "  device : my-kbd  {\n\tbind = , F2, exec, kitty\n  }\n", []string{"my-kbd"}},
+		{"outside of a section", "bind = SUPER, Q, killactive\nbind = SUPER, W, killactive\n", []string{"", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := mustParse(t, tt.content)
+			if len(c.Errors) > 0 {
+				t.Fatalf("errors: %v", c.Errors)
+			}
+			var devices []string
+			for _, kb := range c.Keybinds {
+				devices = append(devices, kb.Device)
+			}
+			if !reflect.DeepEqual(devices, tt.devices) {
+				t.Errorf("got devices %q, want %q", devices, tt.devices)
+			}
+		})
+	}
+}
+
+func TestFilterByDevices(t *testing.T) {
+	c := mustParse(t, "device:mouse {\n    bind = , mouse:275, exec, a\n}\ndevice:kbd {\n    bind = , F1, exec, b\n}\nbind = SUPER, Q, killactive\n")
+	tests := []struct {
+		devices []string
+		want    []string
+	}{
+		{[]string{"mouse"}, []string{"mouse:275"}},
+		{[]string{"KBD"}, []string{"F1"}},
+		{[]string{"mouse", "kbd"}, []string{"mouse:275", "F1"}},
+		{[]string{"touchpad"}, nil},
+		{[]string{""}, nil},
+	}
+	for _, tt := range tests {
+		if got := bindKeys(FilterByDevices(c.Keybinds, tt.devices)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FilterByDevices(%q) = %q, want %q", tt.devices, got, tt.want)
+		}
+	}
+}
+
+func TestExcludeFilters(t *testing.T) {
+	c := mustParse(t, "$mod = SUPER\nbind = $mod, Q, killactive\nbind = $mod SHIFT, E, exec, kitty\nbind = ALT, T, exec, foot\nbind = ALT, F, fullscreen\n")
+	variables := resolvedVariables(c.Variables)
+	tests := []struct {
+		name   string
+		filter func([]Keybind) []Keybind
+		want   []string
+	}{
+		{"exclude a modifier", func(kbs []Keybind) []Keybind { return ExcludeMods(kbs, []string{"SUPER"}, variables) }, []string{"T", "F"}},
+		{"exclude any of the modifiers", func(kbs []Keybind) []Keybind { return ExcludeMods(kbs, []string{"shift", "alt"}, variables) }, []string{"Q"}},
+		{"exclude an alias", func(kbs []Keybind) []Keybind { return ExcludeMods(kbs, []string{"MOD4"}, variables) }, []string{"T", "F"}},
+		{"exclude a dispatcher", func(kbs []Keybind) []Keybind { return ExcludeDispatchers(kbs, []string{"EXEC"}) }, []string{"Q", "F"}},
+		{"include then exclude modifiers", func(kbs []Keybind) []Keybind {
+			return ExcludeMods(FilterByMods(kbs, []string{"SUPER"}, variables), []string{"SHIFT"}, variables)
+		}, []string{"Q"}},
+		{"include a modifier, exclude a dispatcher", func(kbs []Keybind) []Keybind {
+			return ExcludeDispatchers(FilterByMods(kbs, []string{"ALT"}, variables), []string{"exec"})
+		}, []string{"F"}},
+		{"include and exclude the same dispatcher", func(kbs []Keybind) []Keybind {
+			return ExcludeDispatchers(FilterByDispatchers(kbs, []string{"exec"}), []string{"exec"})
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bindKeys(tt.filter(c.Keybinds)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
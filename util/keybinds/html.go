@@ -0,0 +1,131 @@
+package keybinds
+
+import (
+	"html/template"
+	"strings"
+)
+
+// Standalone page used by --html, with just enough inline CSS to print nicely
+var htmlTemplate = template.Must(template.New("html").Funcs(template.FuncMap{
+	"keys": func(kb Keybind) []string {
+		return append(strings.Fields(kb.Mods), kb.DisplayKey())
+	},
+	"title": func(name string) string {
+		return columns[name].title
+	},
+	"cell": func(kb Keybind, name string) string {
+		return columns[name].value(kb)
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Hyprland keybinds</title>
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 60em; color: #222; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2em; }
+th, td { text-align: left; padding: 0.4em 0.8em; border-bottom: 1px solid #ddd; }
+th { background: #f4f4f4; }
+td.keys { white-space: nowrap; width: 1%; }
+kbd { font-family: monospace; padding: 0.1em 0.4em; border: 1px solid #bbb; border-radius: 3px; background: #fafafa; box-shadow: 0 1px 0 #bbb; }
+code { font-family: monospace; }
+@media print { table { page-break-inside: avoid; } }
+</style>
+</head>
+<body>
+<h1>Hyprland keybinds</h1>
+{{- if not .Groups}}
+<p>The config has no binds.</p>
+{{- end}}
+{{- $page := .}}
+{{- range .Groups}}
+<h2>{{.Name}}</h2>
+<table>
+<thead><tr>{{range $page.Columns}}<th>{{title .}}</th>{{end}}</tr></thead>
+<tbody>
+{{- range .Keybinds}}
+{{- $kb := .}}
+<tr>
+{{- range $page.Columns}}
+{{- if eq . "keybind"}}<td class="keys">{{range $i, $key := keys $kb}}{{if $i}}{{$page.Separator}}{{end}}<kbd>{{$key}}</kbd>{{end}}</td>
+{{- else if eq . "command"}}<td><code>{{cell $kb .}}</code></td>
+{{- else}}<td>{{cell $kb .}}</td>
+{{- end}}
+{{- end}}</tr>
+{{- end}}
+</tbody>
+</table>
+{{- end}}
+</body>
+</html>
+`))
+
+// A named group of binds, rendered as one table by --html
+type keybindGroup struct {
+	Name     string
+	Keybinds []Keybind
+}
+
+// Data the --html template is rendered with
+type htmlPage struct {
+	Groups    []keybindGroup
+	Columns   []string // names of the columns of every table, see ColumnNames
+	Separator string   // written between the modifiers and the key
+}
+
+// Group binds by the name name returns for them, in the order each name first appears
+func groupKeybinds(keybinds []Keybind, name func(Keybind) string) []keybindGroup {
+	var groups []keybindGroup
+	index := make(map[string]int)
+	for _, kb := range keybinds {
+		i, ok := index[name(kb)]
+		if !ok {
+			i = len(groups)
+			index[name(kb)] = i
+			groups = append(groups, keybindGroup{Name: name(kb)})
+		}
+		groups[i].Keybinds = append(groups[i].Keybinds, kb)
+	}
+	return groups
+}
+
+// Return the submap a bind belongs to, "Global" for binds outside of one
+func submapName(kb Keybind) string {
+	if kb.Submap == "" {
+		return "Global"
+	}
+	return kb.Submap
+}
+
+// Return the binds of c as a standalone HTML page
+// Binds get a table per submap if the config uses any, binds outside of one are listed as "Global".
+// Otherwise they get a table per dispatcher, which is left out of the columns unless opts.Columns asks for it.
+// Each modifier and the key get their own <kbd>, with the separator of opts between them.
+// Commands are escaped by html/template, so arbitrary shell commands can't break the markup
+func HTML(c Config, opts TableOptions) (string, error) {
+	keybinds := c.Keybinds
+	page := htmlPage{
+		Groups:  groupKeybinds(keybinds, func(kb Keybind) string { return kb.Dispatcher }),
+		Columns: []string{"keybind", "command"},
+	}
+	if opts.ShowFlags {
+		page.Columns = append(page.Columns, "flags")
+	}
+	if hasDescriptions(keybinds) {
+		page.Columns = append(page.Columns, "description")
+	}
+	if hasSubmaps(keybinds) {
+		// The binds aren't grouped by dispatcher, so it needs a column
+		page.Columns = append([]string{"keybind", "dispatcher"}, page.Columns[1:]...)
+		page.Groups = groupKeybinds(keybinds, submapName)
+	}
+
+	if len(opts.Columns) > 0 {
+		page.Columns = opts.Columns
+	}
+	page.Separator = opts.separator()
+
+	var out strings.Builder
+	err := htmlTemplate.Execute(&out, page)
+	return out.String(), err
+}
@@ -0,0 +1,428 @@
+// Package keybinds reads the binds of a Hyprland config and formats them
+package keybinds
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Flag letters Hyprland accepts after the bind keyword, see flagNames
+const bindFlags = "lroenmtisdpcgu"
+
+// Readable names of the bind flags, shown by --show-flags
+var flagNames = map[rune]string{
+	'l': "locked",
+	'r': "release",
+	'o': "long press",
+	'e': "repeat",
+	'n': "non-consuming",
+	'm': "mouse",
+	't': "transparent",
+	'i': "ignore mods",
+	's': "separate",
+	'd': "description",
+	'p': "bypass inhibitors",
+	'c': "click",
+	'g': "drag",
+	'u': "submap universal",
+}
+
+// Return true if line declares a bind: the bind keyword, any flag letters and the equals sign
+// Whitespace is allowed before the keyword and around the equals sign.
+// Other words starting with bind, like `bindword = x`, aren't binds, see unknownBindFlags.
+// Every line of the config goes through this, so it is written out rather than a regexp, which was ~3x slower
+func isBindLine(line string) bool {
+	rest := strings.TrimLeft(line, " \t")
+	if !strings.HasPrefix(rest, "bind") {
+		return false
+	}
+	rest = strings.TrimLeft(rest[len("bind"):], bindFlags)
+	return strings.HasPrefix(strings.TrimLeft(rest, " \t"), "=")
+}
+
+// Return the flag letters Hyprland doesn't know of a line like `bindx = ...`, empty if line isn't one
+// Hyprland reads any bind keyword followed by letters as a bind and refuses the line, so it is reported rather than ignored
+func unknownBindFlags(line string) string {
+	keyword, _, ok := strings.Cut(line, "=")
+	keyword = strings.TrimSpace(keyword)
+	if !ok || !strings.HasPrefix(keyword, "bind") {
+		return ""
+	}
+	var unknown strings.Builder
+	for _, flag := range keyword[len("bind"):] {
+		if flag < 'a' || flag > 'z' {
+			return ""
+		}
+		if !strings.ContainsRune(bindFlags, flag) {
+			unknown.WriteRune(flag)
+		}
+	}
+	return unknown.String()
+}
+
+// Return true if keyword, the part of a line before its equals sign, is the bind keyword with any flag letters
+// Only the keyword counts, a line like `exec-once = hyprctl keyword bind SUPER, X, exec, kitty` isn't a bind
+func IsBindKeyword(keyword string) bool {
+	return strings.HasPrefix(keyword, "bind") && strings.Trim(keyword[len("bind"):], bindFlags) == ""
+}
+
+// Matches the line opening a device section, either `device:name {` or `device {` followed by a name= line
+var deviceRegexp = regexp.MustCompile(`^\s*device\s*(?::\s*(.*?))?\s*\{\s*$`)
+
+// A bind read from the config, split into its fields
+// The JSON and YAML field names make up the --json and --yaml output, keep them stable
+type Keybind struct {
+	Mods        string   `json:"modifiers" yaml:"modifiers"`       // modifier keys as written, empty if the bind has none, see SetMods
+	Modifiers   []string `json:"-" yaml:"-"`                       // Mods split into the modifiers, e.g. ["SUPER", "SHIFT"], --json has just Mods
+	Key         string   `json:"key" yaml:"key"`                   // key or mouse button the bind is triggered by, as written
+	KeyName     string   `json:"key_name" yaml:"key_name"`         // readable name of the key, set by Normalize, see KeyName
+	Dispatcher  string   `json:"dispatcher" yaml:"dispatcher"`     // dispatcher the bind calls, e.g. exec
+	Args        string   `json:"command" yaml:"command"`           // arguments passed to the dispatcher, as written
+	CommandName string   `json:"command_name" yaml:"command_name"` // readable form of the command, set by Normalize, see CommandName
+	Flags       string   `json:"flags" yaml:"flags"`               // flag letters following the bind keyword, e.g. "le" for bindle=
+	Submap      string   `json:"submap" yaml:"submap"`             // submap the bind belongs to, empty outside of one
+	Device      string   `json:"device" yaml:"device"`             // device section the bind is written in, empty outside of one
+	Description string   `json:"description" yaml:"description"`   // description given to a bindd=, or its comment with --comments
+	SourceFile  string   `json:"file" yaml:"file"`                 // file the bind was read from, empty for a config given to Parse
+	LineNumber  int      `json:"line" yaml:"line"`                 // line of SourceFile the bind is on, starting at 1
+	Comment     string   `json:"-" yaml:"-"`                       // comment written above or after the bind
+	Line        string   `json:"-" yaml:"-"`                       // the line as written in the config
+}
+
+// The binds and variables read from a config
+type Config struct {
+	Keybinds  []Keybind
+	Variables map[string]string // variables defined in the config by name, unresolved, see ResolveVariables
+	Defined   map[string]Line   // where each of the variables is given the value it has
+	Files     []string          // files read by Read, the config itself first and then the files it sources
+	Errors    []BindError       // bind lines that couldn't be parsed, they are left out of Keybinds
+	Rules     []WindowRule      // windowrule= and windowrulev2= lines, in the order they are read
+	Warnings  []error           // problems that didn't stop reading the config, like a sourced file that couldn't be read
+}
+
+// A line of a config
+type Line struct {
+	File string // file the line is in, empty for a config given to Parse
+	Line int    // line number, starting at 1
+}
+
+// A bind line that couldn't be parsed
+type BindError struct {
+	File   string // file the line is in, empty for a config given to Parse
+	Line   int    // line number, starting at 1
+	Text   string // the line as written
+	Reason error
+}
+
+func (e BindError) Error() string {
+	return fmt.Sprintf("%s: %v: %s", location(e.File, e.Line), e.Reason, strings.TrimSpace(e.Text))
+}
+
+// Return where a line is, as "file:line" or "line N" when the file isn't known
+func location(file string, line int) string {
+	if file == "" {
+		return fmt.Sprintf("line %d", line)
+	}
+	return fmt.Sprintf("%s:%d", file, line)
+}
+
+func (e BindError) Unwrap() error {
+	return e.Reason
+}
+
+// Return true if the bind was declared with bindm=
+func (kb Keybind) IsMouse() bool {
+	return strings.Contains(kb.Flags, "m")
+}
+
+// Return true if the bind repeats while its keys are held, it was declared with binde=
+func (kb Keybind) IsRepeat() bool {
+	return strings.Contains(kb.Flags, "e")
+}
+
+// Return where the bind was read from, e.g. "hyprland.conf:12"
+func (kb Keybind) Location() string {
+	return location(kb.SourceFile, kb.LineNumber)
+}
+
+// Return the readable names of the flags of the bind, e.g. "locked, repeat" for bindle=
+func (kb Keybind) FlagNames() string {
+	var names []string
+	for _, flag := range kb.Flags {
+		names = append(names, flagNames[flag])
+	}
+	return strings.Join(names, ", ")
+}
+
+// Return the key of the bind as shown in tables, its KeyName if it has one
+func (kb Keybind) DisplayKey() string {
+	if kb.KeyName != "" {
+		return kb.KeyName
+	}
+	return kb.Key
+}
+
+// Return the command of the bind as shown in tables, its CommandName if it has one
+func (kb Keybind) DisplayCommand() string {
+	if kb.CommandName != "" {
+		return kb.CommandName
+	}
+	return kb.Args
+}
+
+// Separator between the modifiers and the key of a bind, unless --separator picks another
+const DefaultSeparator = " + "
+
+// Return the modifiers and key of the bind as shown in tables, e.g. "SUPER + Q"
+// Binds without modifiers are just their key
+func (kb Keybind) Keys() string {
+	return kb.KeysWith(DefaultSeparator)
+}
+
+// Return the modifiers and key of the bind joined by sep, e.g. "SUPER-Q" for "-"
+func (kb Keybind) KeysWith(sep string) string {
+	if kb.Mods == "" {
+		return kb.DisplayKey()
+	}
+	return kb.Mods + sep + kb.DisplayKey()
+}
+
+// Return the keys of the bind like Keys, followed by its submap if it is in one, e.g. "SUPER + R (submap resize)"
+func (kb Keybind) KeysInSubmap() string {
+	if kb.Submap == "" {
+		return kb.Keys()
+	}
+	return kb.Keys() + " (submap " + kb.Submap + ")"
+}
+
+// Set the modifiers of the bind to mods, both Mods and Modifiers
+// The functions of this package changing the modifiers go through it, so the two always agree
+func (kb *Keybind) SetMods(mods string) {
+	kb.Mods = mods
+	kb.Modifiers = splitMods(mods)
+}
+
+// Return the modifiers written in mods one by one, e.g. ["SUPER", "SHIFT"] for "SUPER_SHIFT"
+func splitMods(mods string) []string {
+	return strings.FieldsFunc(mods, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '_'
+	})
+}
+
+// Return the dispatcher of the bind followed by its arguments, e.g. "exec kitty"
+func (kb Keybind) Action() string {
+	return strings.TrimSpace(kb.Dispatcher + " " + kb.Args)
+}
+
+// Split a bind line into its modifiers, key, dispatcher and command
+// Mouse binds (bindm=) have no command, their third field is the dispatcher.
+// Binds with a description (bindd=) have it as their third field, before the dispatcher.
+// An error is returned for missing fields and unbalanced quotes
+func parseKeybind(line string) (Keybind, error) {
+	// Split on the first equals sign, so `bind = ...` and `bind=...` read the same
+	parts := strings.SplitN(line, "=", 2)
+	keyword := strings.TrimSpace(parts[0])
+	kb := Keybind{
+		Flags: strings.TrimPrefix(keyword, "bind"),
+		Line:  line,
+	}
+	n, required := bindFields(kb.Flags)
+	keybind, comment := splitComment(parts[1], n)
+	kb.Comment = comment
+	if hasUnbalancedQuote(keybind, n) {
+		return kb, errors.New("unbalanced quotes")
+	}
+
+	hasDescription := strings.Contains(kb.Flags, "d")
+	// n is never below required, so splitting into at most n fields finds as many as there are when there are too few
+	fields := splitQuoted(keybind, n)
+	if len(fields) < required {
+		return kb, fmt.Errorf("expected at least %d fields, found %d", required, len(fields))
+	}
+	fields = trimFields(fields, n)
+
+	kb.SetMods(fields[0])
+	kb.Key = fields[1]
+	fields = fields[2:]
+	if hasDescription {
+		kb.Description = fields[0]
+		fields = fields[1:]
+	}
+	kb.Dispatcher = fields[0]
+	if !kb.IsMouse() {
+		kb.Args = fields[1]
+	}
+	if kb.Dispatcher == "" {
+		return kb, errors.New("missing dispatcher")
+	}
+	return kb, nil
+}
+
+// Return how many fields a bind with flags has, and how many of them it must have
+// Mouse binds (bindm=) have no command and binds with a description (bindd=) have one more field.
+// The command is the only field that may be left out
+func bindFields(flags string) (int, int) {
+	n, required := 4, 3
+	if strings.Contains(flags, "m") {
+		n = 3
+	}
+	if strings.Contains(flags, "d") {
+		n++
+		required++
+	}
+	return n, required
+}
+
+// Tells which characters of the comma separated fields of a bind are quoted, as they are read left to right
+// Hyprland doesn't read quotes, so they are only read where they can't change what Hyprland does:
+// a quote opens when it starts a field, after any blanks, e.g. a description like "Close, now".
+// The last of n fields, the command, is kept as written, the apostrophe of `exec, notify-send Don't` quotes nothing.
+// Like in the shell, a backslash escapes the next character in double quotes, e.g. "say \"hi, you\"".
+// With n < 0 there is no last field
+type quoteState struct {
+	n       int  // fields of the bind
+	field   int  // index of the field being read
+	atStart bool // only blanks read of the field yet
+	quote   byte // the quote the field is in, 0 outside of quotes
+	escaped bool // the last character read was a backslash in double quotes
+}
+
+// Return the state of the fields before their first character, see quoteState
+func newQuoteState(n int) quoteState {
+	return quoteState{n: n, atStart: true}
+}
+
+// Return true if the fields are split on commas where the field being read is
+func (q *quoteState) splits() bool {
+	return q.n < 0 || q.field < q.n-1
+}
+
+// Read the next character c, returning true if it is outside of quotes
+func (q *quoteState) next(c byte) bool {
+	switch {
+	case q.escaped:
+		q.escaped = false
+		return false
+	case q.quote == '"' && c == '\\':
+		q.escaped = true
+		return false
+	case q.quote != 0:
+		if c == q.quote {
+			q.quote = 0
+		}
+		return false
+	case q.atStart && (c == '"' || c == '\'') && q.splits():
+		q.quote = c
+		q.atStart = false
+		return false
+	case c == ',' && q.splits():
+		q.field++
+		q.atStart = true
+	case c != ' ' && c != '\t':
+		q.atStart = false
+	}
+	return true
+}
+
+// Split s on the commas outside of quotes into at most n fields, all of them if n < 0
+// Quotes are read like quoteState reads them
+func splitQuoted(s string, n int) []string {
+	var fields []string
+	if n > 0 {
+		fields = make([]string, 0, n)
+	}
+	q := newQuoteState(n)
+	start := 0
+	for i := 0; i < len(s) && q.splits(); i++ {
+		if q.next(s[i]) && s[i] == ',' {
+			fields = append(fields, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(fields, s[start:])
+}
+
+// Return true if a quote opened in the n fields of s is never closed, see quoteState
+func hasUnbalancedQuote(s string, n int) bool {
+	q := newQuoteState(n)
+	for i := 0; i < len(s); i++ {
+		q.next(s[i])
+	}
+	return q.quote != 0
+}
+
+// Split a trailing `# comment` off the n comma separated fields of line, returning them without it and the comment text
+// Only a # at the start of the line or after whitespace starts a comment, and never one inside
+// quotes, e.g. in `bindd = SUPER, N, "#1 done", exec, x`, quotes being read like quoteState reads them.
+// Values that aren't binds are a single field, read as written.
+// ## is Hyprland's escape for a literal #
+func splitComment(line string, n int) (string, string) {
+	// Most lines have no comment, they don't need to be copied
+	if !strings.Contains(line, "#") {
+		return line, ""
+	}
+	var out strings.Builder
+	out.Grow(len(line))
+	q := newQuoteState(n)
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if q.next(c) {
+			switch {
+			case c == '#' && i+1 < len(line) && line[i+1] == '#':
+				i++
+			case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+				return strings.TrimRight(out.String(), " \t"), strings.TrimSpace(strings.TrimLeft(line[i:], "#"))
+			}
+		}
+		out.WriteByte(c)
+	}
+	return out.String(), ""
+}
+
+// Return the index of the # starting the comment of line, or -1 if it has none
+// The comment is found like splitComment finds it, the fields of bind lines read like parseKeybind reads them,
+// but the line is left as written, ## included, for callers writing the line back
+func CommentIndex(line string) int {
+	start, n := 0, 1
+	if isBindLine(line) {
+		keyword, _, _ := strings.Cut(line, "=")
+		start = len(keyword) + 1
+		n, _ = bindFields(strings.TrimPrefix(strings.TrimSpace(keyword), "bind"))
+	}
+	q := newQuoteState(n)
+	for i := start; i < len(line); i++ {
+		c := line[i]
+		if !q.next(c) {
+			continue
+		}
+		switch {
+		case c == '#' && i+1 < len(line) && line[i+1] == '#':
+			i++
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return i
+		}
+	}
+	return -1
+}
+
+// Split the comma separated fields of a bind into exactly n fields
+// Only the first n-1 commas separate fields. The last field keeps everything after them verbatim,
+// since dispatcher arguments like `exec, notify-send "a, b"` may contain commas themselves.
+// Commas in quotes never separate fields, see splitQuoted.
+// Binds with fewer fields, like `bind = SUPER, Q, killactive`, are padded with empty strings
+func splitBindFields(keybind string, n int) []string {
+	return trimFields(splitQuoted(keybind, n), n)
+}
+
+// Trim the fields split off a bind and pad them with empty strings up to n fields, see splitBindFields
+func trimFields(fields []string, n int) []string {
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	for len(fields) < n {
+		fields = append(fields, "")
+	}
+	return fields
+}
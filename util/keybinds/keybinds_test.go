@@ -0,0 +1,96 @@
+package keybinds
+
+import "testing"
+
+func TestIsBindLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{"bind = SUPER, Q, killactive", true},
+		{"bindle=, XF86AudioRaiseVolume, exec, up", true},
+		{"  bindm = SUPER, mouse:272, movewindow", true},
+		{"binddr = SUPER, A, Open, exec, x", true},
+		{"bind", false},
+		{"bindword = x", false},
+		{"binds {", false},
+		{"$bind = SUPER", false},
+		{"unbind = SUPER, Q", false},
+		{"exec-once = hyprctl keyword bind SUPER, X, exec, kitty", false},
+		{"# bind = SUPER, Q, killactive", false},
+		{"bindx = SUPER, Q, killactive", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			if got := isBindLine(tt.line); got != tt.want {
+				t.Errorf("isBindLine() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsBindKeyword(t *testing.T) {
+	tests := []struct {
+		keyword string
+		want    bool
+	}{
+		{"bind", true},
+		{"binde", true},
+		{"bindlrm", true},
+		{"binds", true},
+		{"bindx", false},
+		{"rebind", false},
+		{"bind ", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.keyword, func(t *testing.T) {
+			if got := IsBindKeyword(tt.keyword); got != tt.want {
+				t.Errorf("IsBindKeyword(%q) = %v, want %v", tt.keyword, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnknownBindFlags(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"bindx = SUPER, Q, killactive", "x"},
+		{"bindlxy = SUPER, Q, killactive", "xy"},
+		{"bindle = SUPER, Q, killactive", ""},
+		{"bind2 = SUPER, Q, killactive", ""},
+		{"monitor = ,preferred,auto,1", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			if got := unknownBindFlags(tt.line); got != tt.want {
+				t.Errorf("unknownBindFlags() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommentIndex(t *testing.T) {
+	tests := []struct {
+		line string
+		want int
+	}{
+		{"bind = SUPER, Q, exec, kitty # terminal", 29},
+		{"bind = SUPER, D, exec, notify-send Don't # note", 41},
+		{"bind = SUPER, H, exec, echo 'a #b'", 31},
+		{`bindd = SUPER, N, "#1 done", exec, x # note`, 37},
+		{"bind = SUPER, H, exec, echo ## hash", -1},
+		{"$msg = Don't # note", 13},
+		{"gaps_in = 5 # gaps", 12},
+		{"# only a comment", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			if got := CommentIndex(tt.line); got != tt.want {
+				t.Errorf("CommentIndex() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package keybinds
+
+import "strings"
+
+// Names of the mouse buttons binds refer to as mouse:NNN, by their evdev code
+var mouseButtons = map[string]string{
+	"272": "Left Mouse",
+	"273": "Right Mouse",
+	"274": "Middle Mouse",
+	"275": "Mouse Back",
+	"276": "Mouse Forward",
+}
+
+// Names of the scroll wheel directions binds can use as their key
+var mouseWheel = map[string]string{
+	"mouse_up":    "Scroll Up",
+	"mouse_down":  "Scroll Down",
+	"mouse_left":  "Scroll Left",
+	"mouse_right": "Scroll Right",
+}
+
+// Names of the keys binds refer to as code:NN, by their xkb keycode on a US layout
+// Other layouts move the letters around, but this covers what keycodes are mostly used for
+var keyCodes = map[string]string{
+	"9": "Escape", "10": "1", "11": "2", "12": "3", "13": "4", "14": "5", "15": "6", "16": "7", "17": "8",
+	"18": "9", "19": "0", "20": "minus", "21": "equal", "22": "BackSpace", "23": "Tab",
+	"24": "Q", "25": "W", "26": "E", "27": "R", "28": "T", "29": "Y", "30": "U", "31": "I", "32": "O", "33": "P",
+	"34": "bracketleft", "35": "bracketright", "36": "Return", "37": "Control_L",
+	"38": "A", "39": "S", "40": "D", "41": "F", "42": "G", "43": "H", "44": "J", "45": "K", "46": "L",
+	"47": "semicolon", "48": "apostrophe", "49": "grave", "50": "Shift_L", "51": "backslash",
+	"52": "Z", "53": "X", "54": "C", "55": "V", "56": "B", "57": "N", "58": "M",
+	"59": "comma", "60": "period", "61": "slash", "62": "Shift_R", "64": "Alt_L", "65": "space", "66": "Caps_Lock",
+	"67": "F1", "68": "F2", "69": "F3", "70": "F4", "71": "F5", "72": "F6", "73": "F7", "74": "F8", "75": "F9",
+	"76": "F10", "95": "F11", "96": "F12", "107": "Print", "108": "Alt_R", "105": "Control_R",
+	"110": "Home", "111": "Up", "112": "Prior", "113": "Left", "114": "Right", "115": "End", "116": "Down",
+	"117": "Next", "118": "Insert", "119": "Delete", "133": "Super_L", "134": "Super_R",
+}
+
+// Return a readable name for a key, e.g. "Left Mouse" for mouse:272 or "Return" for code:36
+// Keys without a better name are returned as they are
+func KeyName(key string) string {
+	if code := strings.TrimPrefix(key, "code:"); code != key {
+		if name, ok := keyCodes[code]; ok {
+			return name
+		}
+		return key
+	}
+	if name, ok := mouseWheel[strings.ToLower(key)]; ok {
+		return name
+	}
+	if code := strings.TrimPrefix(key, "mouse:"); code != key {
+		if name, ok := mouseButtons[code]; ok {
+			return name
+		}
+		return "Mouse " + code
+	}
+	return key
+}
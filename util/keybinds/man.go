@@ -0,0 +1,48 @@
+package keybinds
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// Escapes roff needs in text, backslashes first so the others aren't escaped twice
+var roffReplacer = strings.NewReplacer(`\`, `\e`, "-", `\-`)
+
+// Write the binds of c as a roff man page, for `man -l` or installing into a man directory
+// Binds get a section per submap if the config uses any, otherwise a section per dispatcher, like with HTML
+func WriteMan(w io.Writer, c Config) error {
+	groups := groupKeybinds(c.Keybinds, func(kb Keybind) string { return kb.Dispatcher })
+	bySubmap := hasSubmaps(c.Keybinds)
+	if bySubmap {
+		groups = groupKeybinds(c.Keybinds, submapName)
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(".TH HYPRKEYS 7 \"\" \"hyprkeys\" \"Hyprland keybinds\"\n")
+	bw.WriteString(".SH NAME\nhyprkeys \\- keybinds of a Hyprland config\n")
+	for _, group := range groups {
+		bw.WriteString(".SH " + roffLine(strings.ToUpper(group.Name)) + "\n")
+		for _, kb := range group.Keybinds {
+			action := kb.DisplayCommand()
+			if bySubmap || action == "" {
+				action = strings.TrimSpace(kb.Dispatcher + " " + action)
+			}
+			bw.WriteString(".TP\n.B " + roffLine(kb.Keys()) + "\n" + roffLine(action) + "\n")
+			if kb.Description != "" {
+				bw.WriteString(".br\n" + roffLine(kb.Description) + "\n")
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// Escape s for a line of roff text
+// A line starting with a dot or an apostrophe would be read as a request, \& keeps it text
+func roffLine(s string) string {
+	s = roffReplacer.Replace(s)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
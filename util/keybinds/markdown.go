@@ -0,0 +1,242 @@
+package keybinds
+
+import (
+	"sort"
+	"strings"
+)
+
+// How the markdown, CSV and plain tables are laid out
+type TableOptions struct {
+	NoHeader  bool     // leave out the header row, and the separator row of markdown tables
+	Columns   []string // columns of the markdown, CSV and HTML tables in order, see ColumnNames. Empty keeps the default ones
+	Separator string   // separator between the modifiers and the key, empty for DefaultSeparator
+	ShowFlags bool     // add a Flags column to the default markdown and HTML columns
+	KbdStyle  string   // how the markdown table wraps keys, one of KbdStyles, empty for kbd
+	GroupBy   string   // split the markdown table into a section per group, one of GroupByNames, empty for a single table
+	// label the keys of binds that repeat while held, declared with binde=, with RepeatsLabel in the plain and markdown tables
+	MarkRepeats bool
+}
+
+// Label the plain and markdown tables add to the keys of repeating binds with TableOptions.MarkRepeats
+const RepeatsLabel = " (repeats)"
+
+// What the markdown table can be grouped by
+var GroupByNames = []string{"submap", "dispatcher", "mod"}
+
+// Heading of the binds that aren't in any group, like the binds outside of submaps
+const ungroupedHeading = "General"
+
+// Return the name of the group a bind belongs to with --group-by, empty if it belongs to none
+func groupName(kb Keybind, by string) string {
+	switch by {
+	case "submap":
+		return kb.Submap
+	case "dispatcher":
+		return kb.Dispatcher
+	case "mod":
+		return kb.Mods
+	}
+	return ""
+}
+
+// Return the binds of c as a markdown section per group, an H2 heading followed by the table of its binds
+// Binds in no group come first under ungroupedHeading, the groups follow in the order they first appear in
+func markdownGroups(c Config, opts TableOptions) []string {
+	by := opts.GroupBy
+	opts.GroupBy = ""
+
+	var order []string
+	groups := make(map[string][]Keybind)
+	for _, kb := range c.Keybinds {
+		name := groupName(kb, by)
+		if _, ok := groups[name]; !ok {
+			order = append(order, name)
+		}
+		// The heading already says which submap the binds are in
+		if by == "submap" {
+			kb.Submap = ""
+		}
+		groups[name] = append(groups[name], kb)
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return order[i] == "" && order[j] != ""
+	})
+
+	var markdown []string
+	for i, name := range order {
+		heading := name
+		if heading == "" {
+			heading = ungroupedHeading
+		}
+		if i > 0 {
+			markdown = append(markdown, "")
+		}
+		markdown = append(markdown, "## "+heading, "")
+		markdown = append(markdown, Markdown(Config{Keybinds: groups[name]}, opts)...)
+	}
+	return markdown
+}
+
+// Ways the markdown table can wrap keys: in <kbd> tags, a code span, bold or not at all
+var KbdStyles = []string{"kbd", "code", "bold", "plain"}
+
+// Return the separator of opts, DefaultSeparator unless one was given
+func (opts TableOptions) separator() string {
+	if opts.Separator == "" {
+		return DefaultSeparator
+	}
+	return opts.Separator
+}
+
+// Return the key of a bind as shown in the tables, labeled when it repeats and opts.MarkRepeats is set
+func (opts TableOptions) displayKey(kb Keybind) string {
+	if opts.MarkRepeats && kb.IsRepeat() {
+		return kb.DisplayKey() + RepeatsLabel
+	}
+	return kb.DisplayKey()
+}
+
+// Return the modifiers and key of a bind joined by the separator of opts, like KeysWith
+func (opts TableOptions) keys(kb Keybind) string {
+	if kb.Mods == "" {
+		return opts.displayKey(kb)
+	}
+	return kb.Mods + opts.separator() + opts.displayKey(kb)
+}
+
+// Return the keys of a bind as a markdown cell, wrapped the way opts.KbdStyle says
+func (opts TableOptions) markdownKeys(kb Keybind) string {
+	keys := opts.keys(kb)
+	switch opts.KbdStyle {
+	case "code":
+		return "`" + keys + "`"
+	case "bold":
+		return "**" + keys + "**"
+	case "plain":
+		return keys
+	}
+	return "<kbd>" + keys + "</kbd>"
+}
+
+// Return the binds of c as a markdown table, header included unless opts.NoHeader is set
+// Each bind becomes a row like this: | <kbd>SUPER + L</kbd> | exec | firefox |, see TableOptions.KbdStyle
+// we also account for no MOD key.
+// A Submap column is added when any bind belongs to a submap,
+// and a Description column when any bind has a description.
+// Mouse binds (bindm=) have no command, so they get an empty dispatcher column
+func Markdown(c Config, opts TableOptions) []string {
+	if opts.GroupBy != "" {
+		return markdownGroups(c, opts)
+	}
+	if len(opts.Columns) > 0 {
+		return markdownColumns(c, opts)
+	}
+	keybinds := c.Keybinds
+	showSubmap := hasSubmaps(keybinds)
+	showDescription := hasDescriptions(keybinds)
+
+	header := []string{"Keybind", "Dispatcher", "Command"}
+	if opts.ShowFlags {
+		header = append(header, "Flags")
+	}
+	if showSubmap {
+		header = append(header, "Submap")
+	}
+	if showDescription {
+		header = append(header, "Description")
+	}
+	markdown := markdownHeader(header, opts)
+
+	for _, mouse := range []bool{false, true} {
+		for _, kb := range keybinds {
+			if kb.IsMouse() != mouse {
+				continue
+			}
+
+			keys := opts.markdownKeys(kb)
+
+			// leave the dispatcher column of mouse binds empty
+			row := []string{keys, kb.Dispatcher, kb.DisplayCommand()}
+			if mouse {
+				row = []string{keys, "", kb.Dispatcher}
+			}
+			if opts.ShowFlags {
+				row = append(row, kb.FlagNames())
+			}
+			if showSubmap {
+				row = append(row, kb.Submap)
+			}
+			if showDescription {
+				row = append(row, kb.Description)
+			}
+			markdown = append(markdown, markdownRow(row))
+		}
+	}
+
+	return markdown
+}
+
+// Return the binds of c as a markdown table with the columns of opts.Columns, in the order of the config
+func markdownColumns(c Config, opts TableOptions) []string {
+	header := make([]string, len(opts.Columns))
+	for i, name := range opts.Columns {
+		header[i] = columns[name].title
+	}
+	markdown := markdownHeader(header, opts)
+	for _, kb := range c.Keybinds {
+		row := make([]string, len(opts.Columns))
+		for i, name := range opts.Columns {
+			row[i] = columns[name].value(kb)
+			switch name {
+			case "keybind":
+				row[i] = opts.markdownKeys(kb)
+			case "key":
+				row[i] = opts.displayKey(kb)
+			}
+		}
+		markdown = append(markdown, markdownRow(row))
+	}
+	return markdown
+}
+
+// Return the cells as a markdown table row
+// A | in a cell would end it, like in `grim -g "$(slurp)" - | wl-copy`, so it is escaped
+func markdownRow(cells []string) string {
+	escaped := make([]string, len(cells))
+	for i, cell := range cells {
+		escaped[i] = strings.ReplaceAll(cell, "|", `\|`)
+	}
+	return "| " + strings.Join(escaped, " | ") + " |"
+}
+
+// Return the header and separator rows of a markdown table, none if opts.NoHeader is set
+func markdownHeader(header []string, opts TableOptions) []string {
+	if opts.NoHeader {
+		return nil
+	}
+	separator := make([]string, len(header))
+	for i, name := range header {
+		separator[i] = strings.Repeat("-", len(name)+2)
+	}
+	return []string{"| " + strings.Join(header, " | ") + " |", "|" + strings.Join(separator, "|") + "|"}
+}
+
+// Return true if any of the binds has a description
+func hasDescriptions(keybinds []Keybind) bool {
+	for _, kb := range keybinds {
+		if kb.Description != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Return true if any of the binds belongs to a submap
+func hasSubmaps(keybinds []Keybind) bool {
+	for _, kb := range keybinds {
+		if kb.Submap != "" {
+			return true
+		}
+	}
+	return false
+}
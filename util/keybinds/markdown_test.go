@@ -0,0 +1,40 @@
+package keybinds
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMarkRepeats(t *testing.T) {
+	c := mustParse(t, "binde = , XF86AudioRaiseVolume, exec, wpctl set-volume @DEFAULT_SINK@ 5%+\nbind = SUPER, Q, killactive\nbindel = , XF86MonBrightnessUp, exec, brightnessctl s +5%\n")
+	tables := []struct {
+		name  string
+		write func(TableOptions) string
+	}{
+		{"markdown", func(opts TableOptions) string { return strings.Join(Markdown(c, opts), "\n") }},
+		{"plain", func(opts TableOptions) string {
+			var out bytes.Buffer
+			if err := WritePlain(&out, c, PlainOptions{TableOptions: opts}); err != nil {
+				t.Fatal(err)
+			}
+			return out.String()
+		}},
+	}
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			marked := table.write(TableOptions{MarkRepeats: true})
+			for _, key := range []string{"XF86AudioRaiseVolume", "XF86MonBrightnessUp"} {
+				if !strings.Contains(marked, key+RepeatsLabel) {
+					t.Errorf("%s isn't labeled as repeating:\n%s", key, marked)
+				}
+			}
+			if strings.Count(marked, RepeatsLabel) != 2 {
+				t.Errorf("got %d labels, want 2:\n%s", strings.Count(marked, RepeatsLabel), marked)
+			}
+			if plain := table.write(TableOptions{}); strings.Contains(plain, RepeatsLabel) {
+				t.Errorf("labeled without MarkRepeats:\n%s", plain)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package keybinds
+
+import "strings"
+
+// Canonical modifier names, in the order they are printed
+var modifierOrder = []string{"SUPER", "CTRL", "ALT", "SHIFT", "CAPS", "MOD2", "MOD3", "MOD5"}
+
+// Spellings of the modifiers Hyprland accepts, mapped to their canonical name
+// To support another spelling, add it here
+var modifierAliases = map[string]string{
+	"SUPER":   "SUPER",
+	"WIN":     "SUPER",
+	"LOGO":    "SUPER",
+	"MOD4":    "SUPER",
+	"CTRL":    "CTRL",
+	"CONTROL": "CTRL",
+	"ALT":     "ALT",
+	"MOD1":    "ALT",
+	"SHIFT":   "SHIFT",
+	"CAPS":    "CAPS",
+	"MOD2":    "MOD2",
+	"MOD3":    "MOD3",
+	"MOD5":    "MOD5",
+}
+
+// Return the modifiers of a bind with canonical names, in a consistent order
+// e.g. "shift MOD4" becomes "SUPER SHIFT". Tokens that aren't modifiers, usually
+// unresolved variables like $mainMod, are kept as they are in front of the known modifiers
+func NormalizeMods(mods string) string {
+	tokens := strings.FieldsFunc(mods, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '_'
+	})
+
+	known := make(map[string]bool)
+	var unknown []string
+	for _, token := range tokens {
+		if name, ok := modifierAliases[strings.ToUpper(token)]; ok {
+			known[name] = true
+		} else {
+			unknown = append(unknown, token)
+		}
+	}
+
+	normalized := unknown
+	for _, name := range modifierOrder {
+		if known[name] {
+			normalized = append(normalized, name)
+		}
+	}
+	return strings.Join(normalized, " ")
+}
+
+// Normalize the modifiers of every bind and give their keys and commands a readable name,
+// see NormalizeMods, KeyName and CommandName.
+// Variables should be substituted first, so the names are looked up for their values
+func Normalize(keybinds []Keybind) {
+	for i := range keybinds {
+		keybinds[i].SetMods(NormalizeMods(keybinds[i].Mods))
+		keybinds[i].KeyName = KeyName(keybinds[i].Key)
+		keybinds[i].CommandName = CommandName(keybinds[i].Dispatcher, keybinds[i].Args)
+	}
+}
+
+// Return an identity for the modifier+key combination a bind is triggered by
+// Modifiers are compared with their canonical names regardless of order, keys by their name regardless of case,
+// so SUPER SHIFT, Q and shift mod4, q and SUPER SHIFT, code:24 are the same combination.
+// Binds in different submaps never share a combination
+func ComboKey(kb Keybind) string {
+	combo := strings.ToUpper(NormalizeMods(kb.Mods)) + " + " + strings.ToLower(KeyName(kb.Key))
+	if kb.Submap != "" {
+		combo += " (submap " + kb.Submap + ")"
+	}
+	return combo
+}
+
+// Return the groups of binds that share a modifier+key combination
+// Groups are in the order their combination first appears in the config
+func FindConflicts(keybinds []Keybind) [][]Keybind {
+	var order []string
+	groups := make(map[string][]Keybind)
+	for _, kb := range keybinds {
+		combo := ComboKey(kb)
+		if _, ok := groups[combo]; !ok {
+			order = append(order, combo)
+		}
+		groups[combo] = append(groups[combo], kb)
+	}
+
+	var conflicts [][]Keybind
+	for _, combo := range order {
+		if len(groups[combo]) > 1 {
+			conflicts = append(conflicts, groups[combo])
+		}
+	}
+	return conflicts
+}
@@ -0,0 +1,150 @@
+package keybinds
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Spaces between the columns of the plain table
+const plainPadding = 2
+
+// Commands and descriptions aren't truncated below this many characters, however narrow the terminal is
+const minTruncatedWidth = 10
+
+// ANSI escapes the plain table is colored with
+const (
+	colorHeader     = "\x1b[1m"
+	colorMods       = "\x1b[35m"
+	colorKey        = "\x1b[33m"
+	colorDispatcher = "\x1b[36m"
+	colorReset      = "\x1b[0m"
+)
+
+// How the plain table is laid out
+type PlainOptions struct {
+	TableOptions
+	Width int  // columns the table is fit into, 0 never truncates
+	Color bool // color the header, modifiers, keys and dispatchers with ANSI escapes
+}
+
+// Write the binds of c as a column aligned table, for reading in a terminal
+// Commands and descriptions that don't fit in opts.Width columns are cut short with an ellipsis.
+// Submap and Description columns are added like in the markdown table
+func WritePlain(w io.Writer, c Config, opts PlainOptions) error {
+	showSubmap := hasSubmaps(c.Keybinds)
+	showDescription := hasDescriptions(c.Keybinds)
+
+	header := []string{"KEYBIND", "DISPATCHER", "COMMAND"}
+	truncated := []int{2}
+	if showSubmap {
+		header = append(header, "SUBMAP")
+	}
+	if showDescription {
+		header = append(header, "DESCRIPTION")
+		truncated = append(truncated, len(header)-1)
+	}
+	rows := [][]string{header}
+	for _, kb := range c.Keybinds {
+		row := []string{opts.keys(kb), kb.Dispatcher, kb.DisplayCommand()}
+		if showSubmap {
+			row = append(row, kb.Submap)
+		}
+		if showDescription {
+			row = append(row, kb.Description)
+		}
+		rows = append(rows, row)
+	}
+
+	widths := columnWidths(rows)
+	if opts.Width > 0 {
+		truncateColumns(rows, widths, opts.Width, truncated)
+	}
+
+	for i, row := range rows {
+		if i == 0 && opts.NoHeader {
+			continue
+		}
+		var line strings.Builder
+		for j, cell := range row {
+			if j > 0 {
+				line.WriteString(strings.Repeat(" ", plainPadding))
+			}
+			pad := strings.Repeat(" ", widths[j]-utf8.RuneCountInString(cell))
+			if opts.Color {
+				if i == 0 {
+					cell = colorHeader + cell + colorReset
+				} else if j == 0 {
+					cell = colorKeys(c.Keybinds[i-1], opts.TableOptions)
+				} else if j == 1 {
+					cell = colorDispatcher + cell + colorReset
+				}
+			}
+			line.WriteString(cell + pad)
+		}
+		// Rows ending in empty cells would be padded up to the last column
+		if _, err := io.WriteString(w, strings.TrimRight(line.String(), " ")+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Return the keys of a bind like Keys does, with the modifiers and the key colored
+func colorKeys(kb Keybind, opts TableOptions) string {
+	key := colorKey + opts.displayKey(kb) + colorReset
+	if kb.Mods == "" {
+		return key
+	}
+	return colorMods + kb.Mods + colorReset + opts.separator() + key
+}
+
+// Return the width of the widest cell of each column
+func columnWidths(rows [][]string) []int {
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			if n := utf8.RuneCountInString(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	return widths
+}
+
+// Truncate the cells of the given columns so the rows fit in width characters once aligned
+// The widest of them is shrunk first, none of them below minTruncatedWidth. widths is updated to match
+func truncateColumns(rows [][]string, widths []int, width int, columns []int) {
+	total := plainPadding * (len(widths) - 1)
+	for _, w := range widths {
+		total += w
+	}
+	for total > width {
+		widest := -1
+		for _, column := range columns {
+			if widths[column] > minTruncatedWidth && (widest == -1 || widths[column] > widths[widest]) {
+				widest = column
+			}
+		}
+		if widest == -1 {
+			break
+		}
+		widths[widest]--
+		total--
+	}
+
+	for _, row := range rows {
+		for _, column := range columns {
+			row[column] = truncate(row[column], widths[column])
+		}
+	}
+}
+
+// Cut s down to width characters, ending it with an ellipsis if anything was cut
+func truncate(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:width-1]) + "…"
+}
@@ -0,0 +1,50 @@
+package keybinds
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Symbols --pretty shows the modifiers as, by canonical name
+// Modifiers without a symbol keep their name
+var DefaultSymbols = map[string]string{
+	"SUPER": "⌘",
+	"SHIFT": "⇧",
+	"CTRL":  "^",
+	"ALT":   "⎇",
+}
+
+// Return DefaultSymbols with the overrides applied, each written as NAME=SYMBOL
+// NAME may be any spelling of a modifier Hyprland accepts, e.g. WIN=⊞ sets the symbol of SUPER
+func ParseSymbols(overrides []string) (map[string]string, error) {
+	symbols := make(map[string]string)
+	for name, symbol := range DefaultSymbols {
+		symbols[name] = symbol
+	}
+	for _, override := range overrides {
+		parts := strings.SplitN(override, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("expected NAME=SYMBOL, got %q", override)
+		}
+		name, ok := modifierAliases[strings.ToUpper(strings.TrimSpace(parts[0]))]
+		if !ok {
+			return nil, fmt.Errorf("unknown modifier %q", parts[0])
+		}
+		symbols[name] = strings.TrimSpace(parts[1])
+	}
+	return symbols, nil
+}
+
+// Replace the modifiers of every bind with their symbol, e.g. "SUPER SHIFT" becomes "⌘ ⇧"
+// The binds should be normalized first, so the modifiers have their canonical names
+func PrettyMods(keybinds []Keybind, symbols map[string]string) {
+	for i := range keybinds {
+		mods := splitMods(keybinds[i].Mods)
+		for j, mod := range mods {
+			if symbol, ok := symbols[mod]; ok {
+				mods[j] = symbol
+			}
+		}
+		keybinds[i].SetMods(strings.Join(mods, " "))
+	}
+}
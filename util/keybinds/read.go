@@ -0,0 +1,389 @@
+package keybinds
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Parse the binds and variables of a config
+// source= lines in it are resolved against the working directory
+func Parse(content []byte) (Config, error) {
+	cs := newConfigScanner()
+	if err := cs.scan(bytes.NewReader(content), "", "."); err != nil {
+		return Config{}, err
+	}
+	return cs.config(), nil
+}
+
+// Return the binds of a config the way hyprkeys prints them by default,
+// with its variables substituted and its modifiers, keys and commands normalized
+func ParseKeybinds(content []byte) ([]Keybind, error) {
+	c, err := Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	SubstituteVariables(c.Keybinds, resolvedVariables(c.Variables))
+	Normalize(c.Keybinds)
+	return c.Keybinds, nil
+}
+
+// Return several configs as one, with their binds, files and errors in the order the configs are given
+// A variable defined in more than one of them keeps its first value
+func Merge(configs ...Config) Config {
+	merged := Config{Variables: make(map[string]string), Defined: make(map[string]Line)}
+	for _, c := range configs {
+		merged.Keybinds = append(merged.Keybinds, c.Keybinds...)
+		merged.Files = append(merged.Files, c.Files...)
+		merged.Errors = append(merged.Errors, c.Errors...)
+		merged.Rules = append(merged.Rules, c.Rules...)
+		merged.Warnings = append(merged.Warnings, c.Warnings...)
+		for name, value := range c.Variables {
+			if _, ok := merged.Variables[name]; !ok {
+				merged.Variables[name] = value
+				merged.Defined[name] = c.Defined[name]
+			}
+		}
+	}
+	return merged
+}
+
+// Return the config at path as a single file, with the files it sources written in place of their source= lines
+// The source= lines are kept as comments, so it's clear where each file starts.
+// With resolve set, variables are replaced with their values everywhere but in the lines defining them.
+// Sourced files that can't be read are left out, Read returns them as warnings
+func Flatten(path string, resolve bool) (string, error) {
+	if err := checkConfigFile(path); err != nil {
+		return "", err
+	}
+
+	cs := newConfigScanner()
+	cs.flat = &strings.Builder{}
+	if err := cs.scanFile(path); err != nil {
+		return "", fmt.Errorf("reading config: %w", err)
+	}
+	flat := cs.flat.String()
+	if !resolve {
+		return flat, nil
+	}
+
+	replacer := variableReplacer(resolvedVariables(cs.variableMap))
+	lines := strings.SplitAfter(flat, "\n")
+	for i, line := range lines {
+		if !strings.HasPrefix(strings.TrimSpace(line), "$") {
+			lines[i] = replacer.Replace(line)
+		}
+	}
+	return strings.Join(lines, ""), nil
+}
+
+// Read the config at path and the files it includes with source=, in the order they are included
+func Read(path string) (Config, error) {
+	if err := checkConfigFile(path); err != nil {
+		return Config{}, err
+	}
+
+	cs := newConfigScanner()
+	if err := cs.scanFile(path); err != nil {
+		return Config{}, fmt.Errorf("reading config: %w", err)
+	}
+	return cs.config(), nil
+}
+
+// Return an error if there is no config file at path, or it is a directory
+// Reading a directory fails with a cryptic error, so it is caught before
+func checkConfigFile(path string) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("config file %s does not exist", path)
+	}
+	if err == nil && info.IsDir() {
+		return fmt.Errorf("config file %s is a directory, expected a file like %s", path, filepath.Join(path, "hyprland.conf"))
+	}
+	return nil
+}
+
+// State kept while reading a config and the files it sources
+type configScanner struct {
+	visited     map[string]bool // absolute paths already read, so include loops don't recurse forever
+	files       []string
+	keybinds    []Keybind
+	errors      []BindError
+	rules       []WindowRule
+	warnings    []error
+	variableMap map[string]string
+	defined     map[string]Line
+	replacer    *strings.Replacer // substitutes the variables read so far, nil until needed, see variables
+	comboKeys   []string          // ComboKey of the first binds with the variables substituted, see unbind
+	submap      string            // submap the binds being read belong to, empty outside of one
+	inDevice    bool              // whether the lines being read are in a device section
+	// when set, every line read is written to it, with source= lines commented out and followed by the file they source
+	flat   *strings.Builder
+	device string // name of that device section
+}
+
+func newConfigScanner() *configScanner {
+	return &configScanner{
+		visited:     make(map[string]bool),
+		variableMap: make(map[string]string),
+		defined:     make(map[string]Line),
+	}
+}
+
+// Return what was read as a Config
+func (cs *configScanner) config() Config {
+	return Config{Keybinds: cs.keybinds, Variables: cs.variableMap, Defined: cs.defined, Files: cs.files, Errors: cs.errors, Rules: cs.rules, Warnings: cs.warnings}
+}
+
+// Scan a single config file, collecting its binds and variables
+func (cs *configScanner) scanFile(configPath string) error {
+	absPath, err := filepath.Abs(configPath)
+	if err != nil {
+		return err
+	}
+	if cs.visited[absPath] {
+		cs.warnings = append(cs.warnings, fmt.Errorf("skipping %s, it was already sourced", configPath))
+		return nil
+	}
+	cs.visited[absPath] = true
+
+	if info, err := os.Stat(configPath); err == nil && info.IsDir() {
+		return fmt.Errorf("%s is a directory", configPath)
+	}
+
+	// Open the file
+	file, err := os.Open(configPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	cs.files = append(cs.files, configPath)
+
+	r, err := decompress(file)
+	if err != nil {
+		return fmt.Errorf("%s: %w", configPath, err)
+	}
+	return cs.scan(r, configPath, filepath.Dir(absPath))
+}
+
+// Bytes gzip compressed files start with
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// Return a reader of the config read from r, decompressed if it is gzip compressed, like a hyprland.conf.gz
+// Compressed configs are told apart by their first bytes, whatever their name
+func decompress(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(len(gzipMagic)); !bytes.Equal(magic, gzipMagic) {
+		return br, nil
+	}
+	return gzip.NewReader(br)
+}
+
+// Return the content of a config, decompressed if it is gzip compressed, see decompress
+func Decompress(content []byte) ([]byte, error) {
+	if !bytes.HasPrefix(content, gzipMagic) {
+		return content, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// Scan the lines of a config, name is the file they are read from and dir the directory
+// relative source= paths are resolved against. source= lines are followed recursively
+func (cs *configScanner) scan(r io.Reader, name string, dir string) error {
+	scanner := bufio.NewScanner(r)
+
+	// comment lines right above the current line, they describe the bind that follows them
+	comment := ""
+
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		// Configs saved on Windows end their lines with \r\n, the \r would end up in the last field of binds
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+
+		if cs.flat != nil {
+			if _, ok := parseKeywordLine(line, "source"); ok {
+				cs.flat.WriteString("# " + strings.TrimSpace(line) + "\n")
+			} else {
+				cs.flat.WriteString(line + "\n")
+			}
+		}
+
+		// Comments are never binds or variables, even when they look like one
+		if text := strings.TrimSpace(line); strings.HasPrefix(text, "#") {
+			text = strings.TrimSpace(strings.TrimLeft(text, "#"))
+			// A commented out bind is a disabled bind, not a description of the one below it
+			if isBindLine(text) {
+				comment = ""
+				continue
+			}
+			if comment != "" && text != "" {
+				comment += " "
+			}
+			comment += text
+			continue
+		}
+
+		if isBindLine(line) {
+			// If the line starts with any bind type, append it to the keybinds slice
+			// A comment after the bind describes it better than the one above it
+			kb, err := parseKeybind(line)
+			if err != nil {
+				cs.errors = append(cs.errors, BindError{File: name, Line: lineNumber, Text: line, Reason: err})
+				comment = ""
+				continue
+			}
+			kb.Submap = cs.submap
+			kb.Device = cs.device
+			kb.SourceFile = name
+			kb.LineNumber = lineNumber
+			if kb.Comment == "" {
+				kb.Comment = comment
+			}
+			cs.keybinds = append(cs.keybinds, kb)
+
+		} else if flags := unknownBindFlags(line); flags != "" {
+			cs.errors = append(cs.errors, BindError{File: name, Line: lineNumber, Text: line, Reason: fmt.Errorf("unknown flag %q", flags)})
+		} else if match := matchDevice(line); match != nil {
+			// Device sections hold settings for a single keyboard or mouse,
+			// binds written in one are labeled with the device
+			cs.inDevice = true
+			cs.device = match[1]
+		} else if cs.inDevice && strings.HasPrefix(strings.TrimSpace(line), "}") {
+			cs.inDevice = false
+			cs.device = ""
+		} else if name, ok := parseKeywordLine(line, "name"); ok && cs.inDevice {
+			cs.device = name
+		} else if strings.HasPrefix(strings.TrimLeft(line, " \t"), "$") {
+			// Probably not the best way to do this, but can't think of another occasion where a line would start with "$"
+			// and include "=", yet still not be a variable
+			if strings.Contains(line, "=") {
+				// Store variables and their values in a map
+				// This will be used to replace variables in the markdown table
+				// with their values
+				// A comment after the value, like `$mainMod = SUPER # main modifier`, isn't part of it
+				variable := strings.SplitN(line, "=", 2)
+				// The spaces around the equals sign aren't part of the name or the value either
+				value, _ := splitComment(variable[1], 1)
+				cs.variableMap[strings.TrimSpace(variable[0])] = strings.TrimSpace(value)
+				cs.defined[strings.TrimSpace(variable[0])] = Line{File: name, Line: lineNumber}
+				cs.replacer = nil
+				cs.comboKeys = cs.comboKeys[:0]
+			}
+		} else if sourcePath, ok := parseKeywordLine(line, "source"); ok {
+			sourcePath = expandSourcePath(sourcePath, dir, cs.variables())
+			// A missing include shouldn't stop us from reading the rest of the config
+			if err := cs.scanFile(sourcePath); err != nil {
+				cs.warnings = append(cs.warnings, fmt.Errorf("could not read sourced file: %w", err))
+			}
+		} else if rule, ok := cs.parseRule(line); ok {
+			rule.SourceFile = name
+			rule.LineNumber = lineNumber
+			cs.rules = append(cs.rules, rule)
+		} else if combo, ok := parseKeywordLine(line, "unbind"); ok {
+			cs.unbind(combo)
+		} else if submap, ok := parseKeywordLine(line, "submap"); ok {
+			// Binds up to the next `submap = reset` belong to this submap
+			if submap == "reset" {
+				submap = ""
+			}
+			cs.submap = submap
+		}
+
+		comment = ""
+	}
+
+	return scanner.Err()
+}
+
+// Remove the binds read so far that are triggered by combo, the value of an `unbind = MODS, KEY` line
+// Combinations are compared like conflicts are, see ComboKey, with the variables defined so far substituted
+func (cs *configScanner) unbind(combo string) {
+	fields := splitBindFields(combo, 2)
+	replacer := cs.variables()
+	target := ComboKey(Keybind{Mods: replacer.Replace(fields[0]), Key: replacer.Replace(fields[1]), Submap: cs.submap})
+
+	// The combinations of the binds are kept for the next unbind= line, until another variable is read
+	for _, kb := range cs.keybinds[len(cs.comboKeys):] {
+		cs.comboKeys = append(cs.comboKeys, ComboKey(Keybind{Mods: replacer.Replace(kb.Mods), Key: replacer.Replace(kb.Key), Submap: kb.Submap}))
+	}
+	kept, keptKeys := cs.keybinds[:0], cs.comboKeys[:0]
+	for i, kb := range cs.keybinds {
+		if cs.comboKeys[i] != target {
+			kept = append(kept, kb)
+			keptKeys = append(keptKeys, cs.comboKeys[i])
+		}
+	}
+	cs.keybinds, cs.comboKeys = kept, keptKeys
+}
+
+// Return a replacer substituting the variables read so far, see variableReplacer
+// It is only built again once another variable is read, configs with many unbind= or source= lines reuse it
+func (cs *configScanner) variables() *strings.Replacer {
+	if cs.replacer == nil {
+		cs.replacer = variableReplacer(resolvedVariables(cs.variableMap))
+	}
+	return cs.replacer
+}
+
+// Return the window rule of a windowrule= or windowrulev2= line, if line is one
+func (cs *configScanner) parseRule(line string) (WindowRule, bool) {
+	for _, keyword := range []string{"windowrule", "windowrulev2"} {
+		if value, ok := parseKeywordLine(line, keyword); ok {
+			return parseWindowRule(keyword, value), true
+		}
+	}
+	return WindowRule{}, false
+}
+
+// Return the submatches of deviceRegexp in line, nil if it doesn't open a device section
+// Lines that can't match are skipped before running the regexp, most lines aren't device sections
+func matchDevice(line string) []string {
+	if !strings.HasPrefix(strings.TrimLeft(line, " \t"), "device") {
+		return nil
+	}
+	return deviceRegexp.FindStringSubmatch(line)
+}
+
+// Return the value of a `keyword = value` line, if line sets keyword
+func parseKeywordLine(line string, keyword string) (string, bool) {
+	name, value, ok := strings.Cut(line, "=")
+	if !ok || strings.TrimSpace(name) != keyword {
+		return "", false
+	}
+	// Drop a trailing comment after the value
+	value, _ = splitComment(value, 1)
+	return strings.TrimSpace(value), true
+}
+
+// Expand the config variables in a sourced path, then a leading ~ and environment variables, see ExpandPath
+// Relative paths are resolved against the directory of the file that sourced them
+func expandSourcePath(path string, dir string, variables *strings.Replacer) string {
+	path = variables.Replace(path)
+	path = ExpandPath(path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return path
+}
+
+// Expand a leading ~ and environment variables like $HOME or ${XDG_CONFIG_HOME} in path
+// Variables that aren't set are kept as written, so a mistyped name shows up in the error about the path
+func ExpandPath(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		path = "$HOME" + path[1:]
+	}
+	return os.Expand(path, func(name string) string {
+		if value, ok := os.LookupEnv(name); ok {
+			return value
+		}
+		return "$" + name
+	})
+}
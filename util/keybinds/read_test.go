@@ -0,0 +1,483 @@
+package keybinds
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Parse content, failing the test on errors
+func mustParse(t *testing.T, content string) Config {
+	t.Helper()
+	c, err := Parse([]byte(content))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return c
+}
+
+// Parse a config of a single bind line and return the bind
+func parseBind(t *testing.T, line string) Keybind {
+	t.Helper()
+	c := mustParse(t, line+"\n")
+	if len(c.Errors) > 0 {
+		t.Fatalf("%q: %v", line, c.Errors)
+	}
+	if len(c.Keybinds) != 1 {
+		t.Fatalf("%q: found %d binds, want 1", line, len(c.Keybinds))
+	}
+	return c.Keybinds[0]
+}
+
+func TestParseCommandsWithCommas(t *testing.T) {
+	tests := []struct {
+		line       string
+		dispatcher string
+		args       string
+	}{
+		{`bind = SUPER, N, exec, notify-send a, b`, "exec", "notify-send a, b"},
+		{`bind = SUPER, S, movetoworkspace, special:name`, "movetoworkspace", "special:name"},
+		{`bind = SUPER, R, exec, wofi --show drun,run`, "exec", "wofi --show drun,run"},
+		{`bindm = SUPER, mouse:272, movewindow`, "movewindow", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			kb := parseBind(t, tt.line)
+			if kb.Dispatcher != tt.dispatcher || kb.Args != tt.args {
+				t.Errorf("got dispatcher %q and args %q, want %q and %q", kb.Dispatcher, kb.Args, tt.dispatcher, tt.args)
+			}
+		})
+	}
+}
+
+func TestParseMissingFields(t *testing.T) {
+	tests := []struct {
+		line       string
+		dispatcher string // empty when the line is an error
+	}{
+		{`bind = SUPER, L, killactive`, "killactive"},
+		{`bind = SUPER, L, exit,`, "exit"},
+		{`bindm = SUPER, mouse:272`, ""},
+		{`bind = SUPER, L`, ""},
+		{`bind = SUPER`, ""},
+		{`bind =`, ""},
+		{`bind = SUPER, L, , kitty`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			c := mustParse(t, tt.line+"\n")
+			if tt.dispatcher == "" {
+				if len(c.Errors) != 1 || len(c.Keybinds) != 0 {
+					t.Fatalf("got binds %+v and errors %v, want one error", c.Keybinds, c.Errors)
+				}
+				if c.Errors[0].Line != 1 {
+					t.Errorf("error on line %d, want 1", c.Errors[0].Line)
+				}
+				return
+			}
+			if len(c.Keybinds) != 1 || c.Keybinds[0].Dispatcher != tt.dispatcher {
+				t.Errorf("got binds %+v and errors %v, want dispatcher %q", c.Keybinds, c.Errors, tt.dispatcher)
+			}
+		})
+	}
+}
+
+func TestParseKeywordWhitespace(t *testing.T) {
+	tests := []struct {
+		line  string
+		flags string
+	}{
+		{`bind = SUPER, Q, killactive`, ""},
+		{`bind=SUPER,Q,killactive`, ""},
+		{`  bind   =   SUPER, Q, killactive`, ""},
+		{"\tbindle\t= SUPER, Q, killactive", "le"},
+		{`bindm=SUPER, Q, killactive`, "m"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			kb := parseBind(t, tt.line)
+			if kb.Mods != "SUPER" || kb.Key != "Q" || kb.Flags != tt.flags {
+				t.Errorf("got mods %q, key %q and flags %q, want SUPER, Q and %q", kb.Mods, kb.Key, kb.Flags, tt.flags)
+			}
+		})
+	}
+}
+
+func TestParseCommentedOutBinds(t *testing.T) {
+	tests := []struct {
+		content string
+		binds   int
+	}{
+		{"# bind = SUPER, Q, killactive\n", 0},
+		{"   #bind = SUPER, Q, killactive\n", 0},
+		{"\t# $mod = SUPER\nbind = $mod, Q, killactive\n", 1},
+		{"## bind = SUPER, Q, killactive\n", 0},
+		{"bind = SUPER, Q, killactive\n# bind = SUPER, W, killactive\n", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.content, func(t *testing.T) {
+			c := mustParse(t, tt.content)
+			if len(c.Keybinds) != tt.binds || len(c.Errors) > 0 {
+				t.Errorf("got binds %+v and errors %v, want %d binds", c.Keybinds, c.Errors, tt.binds)
+			}
+			if len(c.Variables) > 0 {
+				t.Errorf("a commented out variable was read: %q", c.Variables)
+			}
+		})
+	}
+}
+
+func TestParseTrailingComments(t *testing.T) {
+	tests := []struct {
+		line    string
+		args    string
+		comment string
+	}{
+		{`bind = SUPER, Q, exec, kitty # terminal`, "kitty", "terminal"},
+		{`bind = SUPER, Q, exec, kitty#not a comment`, "kitty#not a comment", ""},
+		{`bind = SUPER, N, exec, notify-send "#1 done" # notify`, `notify-send "#1 done"`, "notify"},
+		{`bind = SUPER, C, exec, hyprpicker 'a #b'`, `hyprpicker 'a`, "b'"},
+		{`bind = SUPER, D, exec, notify-send Don't # apostrophe`, `notify-send Don't`, "apostrophe"},
+		{`bind = SUPER, H, exec, echo ## hash`, "echo # hash", ""},
+		{`bind = SUPER, K, killactive, # close`, "", "close"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			kb := parseBind(t, tt.line)
+			if kb.Args != tt.args || kb.Comment != tt.comment {
+				t.Errorf("got args %q and comment %q, want %q and %q", kb.Args, kb.Comment, tt.args, tt.comment)
+			}
+		})
+	}
+}
+
+func TestParseDescriptions(t *testing.T) {
+	tests := []struct {
+		line        string
+		description string
+		dispatcher  string
+		args        string
+	}{
+		{`bindd = SUPER, Q, Open a terminal, exec, kitty`, "Open a terminal", "exec", "kitty"},
+		{`bindd = SUPER, F, Toggle fullscreen, fullscreen, 0`, "Toggle fullscreen", "fullscreen", "0"},
+		{`binded = , XF86AudioRaiseVolume, Volume up, exec, wpctl set-volume @DEFAULT_SINK@ 5%+`, "Volume up", "exec", "wpctl set-volume @DEFAULT_SINK@ 5%+"},
+		{`bindd = SUPER, K, "Close, now", killactive,`, `"Close, now"`, "killactive", ""},
+		{`bindd = SUPER, K, Don't close, killactive,`, "Don't close", "killactive", ""},
+		{`bindd = SUPER, N, "#1 done", exec, x`, `"#1 done"`, "exec", "x"},
+		{`bind = SUPER, Q, exec, kitty`, "", "exec", "kitty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			kb := parseBind(t, tt.line)
+			if kb.Description != tt.description || kb.Dispatcher != tt.dispatcher || kb.Args != tt.args {
+				t.Errorf("got %q, %q, %q, want %q, %q, %q", kb.Description, kb.Dispatcher, kb.Args, tt.description, tt.dispatcher, tt.args)
+			}
+		})
+	}
+}
+
+// Write files, by name, into a temporary directory and return it
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestExpandPath(t *testing.T) {
+	t.Setenv("HOME", "/home/user")
+	t.Setenv("XDG_CONFIG_HOME", "/xdg")
+	// restored after the test by Setenv
+	t.Setenv("HYPRKEYS_UNSET", "")
+	os.Unsetenv("HYPRKEYS_UNSET")
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"~", "/home/user"},
+		{"~/binds.conf", "/home/user/binds.conf"},
+		{"$HOME/binds.conf", "/home/user/binds.conf"},
+		{"${XDG_CONFIG_HOME}/hypr/binds.conf", "/xdg/hypr/binds.conf"},
+		{"~other/binds.conf", "~other/binds.conf"},
+		{"$HYPRKEYS_UNSET/binds.conf", "$HYPRKEYS_UNSET/binds.conf"},
+		{"/etc/binds.conf", "/etc/binds.conf"},
+		{"binds.conf", "binds.conf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := ExpandPath(tt.path); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadExpandsSourcePaths(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"home", "source = ~/conf/binds.conf"},
+		{"environment variable", "source = $HOME/conf/binds.conf"},
+		{"config variable", "$dir = ~/conf\nsource = $dir/binds.conf"},
+		{"relative", "source = conf/binds.conf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{
+				"hyprland.conf":   tt.source + "\n",
+				"conf/binds.conf": "bind = SUPER, Q, exec, kitty\n",
+			})
+			t.Setenv("HOME", dir)
+			c, err := Read(filepath.Join(dir, "hyprland.conf"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(c.Keybinds) != 1 || len(c.Warnings) > 0 {
+				t.Errorf("got binds %+v and warnings %v, want the bind of binds.conf", c.Keybinds, c.Warnings)
+			}
+		})
+	}
+}
+
+func TestParseUnbind(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string // Keys of the binds left, after their submap
+	}{
+		{"removes the bind", "bind = SUPER, Q, killactive\nbind = SUPER, W, killactive\nunbind = SUPER, Q\n", []string{":SUPER + W"}},
+		{"modifiers in any order", "bind = SUPER SHIFT, Q, killactive\nunbind = SHIFT_SUPER, Q\n", nil},
+		{"key case", "bind = SUPER, q, killactive\nunbind = SUPER, Q\n", nil},
+		{"variables", "$mod = SUPER\nbind = $mod, Q, killactive\nunbind = SUPER, Q\n", nil},
+		{"later binds stay", "bind = SUPER, Q, killactive\nunbind = SUPER, Q\nbind = SUPER, Q, exec, kitty\n", []string{":SUPER + Q"}},
+		{"only in its submap", "bind = SUPER, Q, killactive\nsubmap = resize\nbind = SUPER, Q, killactive\nunbind = SUPER, Q\nsubmap = reset\n", []string{":SUPER + Q"}},
+		{"nothing bound", "bind = SUPER, W, killactive\nunbind = SUPER, Q\n", []string{":SUPER + W"}},
+		{"every bind of the combination", "bind = SUPER, Q, killactive\nbindr = SUPER, Q, exec, x\nunbind = SUPER, Q\n", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := mustParse(t, tt.content)
+			var got []string
+			for _, kb := range c.Keybinds {
+				got = append(got, kb.Submap+":"+kb.Keys())
+			}
+			if !reflect.DeepEqual(got, tt.want) || len(c.Errors) > 0 {
+				t.Errorf("got binds %q and errors %v, want %q", got, c.Errors, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadCRLF(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"hyprland.conf": "$mod = SUPER\r\n# binds\r\nbind = $mod, Q, killactive\r\nbind = $mod, T, exec, kitty\r\nsource = binds.conf\r\n",
+		"binds.conf":    "bindm = $mod, mouse:272, movewindow\r\n",
+	})
+	c, err := Read(filepath.Join(dir, "hyprland.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Errors) > 0 || len(c.Warnings) > 0 {
+		t.Fatalf("got errors %v and warnings %v", c.Errors, c.Warnings)
+	}
+	var got []string
+	for _, kb := range c.Keybinds {
+		got = append(got, kb.Mods+"|"+kb.Key+"|"+kb.Dispatcher+"|"+kb.Args)
+	}
+	want := []string{"$mod|Q|killactive|", "$mod|T|exec|kitty", "$mod|mouse:272|movewindow|"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got binds %q, want %q", got, want)
+	}
+	if mod := c.Variables["$mod"]; mod != "SUPER" {
+		t.Errorf("got $mod %q, want SUPER", mod)
+	}
+}
+
+func TestParseQuotedArguments(t *testing.T) {
+	tests := []struct {
+		line string
+		args string // empty when the line is an error
+	}{
+		{`bind = SUPER, E, exec, sh -c "echo hello, world"`, `sh -c "echo hello, world"`},
+		{`bind = SUPER, E, exec, sh -c 'echo a, b'`, `sh -c 'echo a, b'`},
+		{`bind = SUPER, E, exec, sh -c "echo 'a, b', c"`, `sh -c "echo 'a, b', c"`},
+		{`bind = SUPER, E, exec, sh -c "echo \"a, b\""`, `sh -c "echo \"a, b\""`},
+		{`bind = SUPER, E, exec, sh -c 'it'"'"'s, fine'`, `sh -c 'it'"'"'s, fine'`},
+		{`bind = SUPER, E, exec, echo "unterminated, x`, `echo "unterminated, x`},
+		{`bind = SUPER, E, exec, notify-send Don't`, `notify-send Don't`},
+		{`bind = SUPER, E, exec, notify-send "Don't, stop"`, `notify-send "Don't, stop"`},
+		{`bind = SUPER, E, exec, 'it's`, `'it's`},
+		{`bindd = SUPER, E, "unterminated, exec, x`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			if tt.args == "" {
+				c := mustParse(t, tt.line+"\n")
+				if len(c.Errors) != 1 || len(c.Keybinds) != 0 {
+					t.Errorf("got binds %+v and errors %v, want one error", c.Keybinds, c.Errors)
+				}
+				return
+			}
+			kb := parseBind(t, tt.line)
+			if kb.Key != "E" || kb.Dispatcher != "exec" || kb.Args != tt.args {
+				t.Errorf("got key %q, dispatcher %q and args %q, want E, exec and %q", kb.Key, kb.Dispatcher, kb.Args, tt.args)
+			}
+		})
+	}
+}
+
+func TestParseVariableComments(t *testing.T) {
+	tests := []struct {
+		line  string
+		value string
+	}{
+		{"$mainMod = SUPER # main modifier", "SUPER"},
+		{"$mainMod = SUPER#main", "SUPER#main"},
+		{"$mainMod = SUPER", "SUPER"},
+		{`$notify = notify-send "#1" # notify`, `notify-send "#1"`},
+		{"$hash = a ## b", "a # b"},
+		{"$mainMod = # nothing", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			c := mustParse(t, tt.line+"\nbind = $mainMod, Q, killactive\n")
+			if value, ok := c.Variables[strings.Fields(tt.line)[0]]; !ok || value != tt.value {
+				t.Errorf("got %q, want %q", value, tt.value)
+			}
+		})
+	}
+	c := mustParse(t, "$mainMod = SUPER # main modifier\nbind = $mainMod, Q, killactive\n")
+	SubstituteVariables(c.Keybinds, resolvedVariables(c.Variables))
+	if c.Keybinds[0].Mods != "SUPER" {
+		t.Errorf("the comment ended up in the bind: %q", c.Keybinds[0].Mods)
+	}
+}
+
+func TestParseVariableWhitespace(t *testing.T) {
+	tests := []string{
+		"$mod = SUPER",
+		"$mod=SUPER",
+		"$mod   =   SUPER   ",
+		"$mod\t=\tSUPER\t",
+		"  $mod = SUPER",
+	}
+	for _, line := range tests {
+		t.Run(line, func(t *testing.T) {
+			c := mustParse(t, line+"\nbind = $mod, Q, killactive\n")
+			if !reflect.DeepEqual(c.Variables, map[string]string{"$mod": "SUPER"}) {
+				t.Errorf("got variables %q, want $mod = SUPER", c.Variables)
+			}
+			SubstituteVariables(c.Keybinds, resolvedVariables(c.Variables))
+			if c.Keybinds[0].Mods != "SUPER" {
+				t.Errorf("got mods %q, want SUPER", c.Keybinds[0].Mods)
+			}
+		})
+	}
+}
+
+func TestParseBindInsideCommands(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		args    []string // the command of each bind read
+	}{
+		{"exec bind", "bind = SUPER, X, exec, hyprctl keyword bind SUPER,Y,exec,kitty\n", []string{"hyprctl keyword bind SUPER,Y,exec,kitty"}},
+		{"exec-once", "exec-once = hyprctl keyword bind SUPER, X, exec, kitty\n", nil},
+		{"exec with bind =", "exec = hyprctl keyword bind = SUPER, X, exec, kitty\n", nil},
+		{"bind words in a command", "bind = SUPER, B, exec, echo bind = binde\n", []string{"echo bind = binde"}},
+		{"batch", "bind = SUPER, Z, exec, hyprctl --batch 'keyword unbind SUPER,Z ; keyword bind SUPER,Z,exec,foot'\n", []string{"hyprctl --batch 'keyword unbind SUPER,Z ; keyword bind SUPER,Z,exec,foot'"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := mustParse(t, tt.content)
+			var args []string
+			for _, kb := range c.Keybinds {
+				args = append(args, kb.Args)
+			}
+			if !reflect.DeepEqual(args, tt.args) || len(c.Errors) > 0 {
+				t.Errorf("got commands %q and errors %v, want %q", args, c.Errors, tt.args)
+			}
+		})
+	}
+}
+
+func TestReadGzip(t *testing.T) {
+	plain, err := Read("../../test/hyprland.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed, err := Read("../../test/hyprland.conf.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plain.Keybinds) == 0 {
+		t.Fatal("no binds in test/hyprland.conf")
+	}
+	for i := range compressed.Keybinds {
+		compressed.Keybinds[i].SourceFile = plain.Keybinds[i].SourceFile
+	}
+	if !reflect.DeepEqual(compressed.Keybinds, plain.Keybinds) || !reflect.DeepEqual(compressed.Variables, plain.Variables) {
+		t.Errorf("the compressed config reads differently, got %+v, want %+v", compressed.Keybinds, plain.Keybinds)
+	}
+
+	// Sourced files are decompressed as well
+	dir := writeFiles(t, map[string]string{"hyprland.conf": "source = hyprland.conf.gz\n"})
+	fixture, err := os.ReadFile("../../test/hyprland.conf.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "hyprland.conf.gz"), fixture, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sourced, err := Read(filepath.Join(dir, "hyprland.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sourced.Keybinds) != len(plain.Keybinds) {
+		t.Errorf("got %d binds from the sourced file, want %d", len(sourced.Keybinds), len(plain.Keybinds))
+	}
+}
+
+func TestDecompress(t *testing.T) {
+	plain, err := os.ReadFile("../../test/hyprland.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed, err := os.ReadFile("../../test/hyprland.conf.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		content []byte
+		want    []byte
+		wantErr bool
+	}{
+		{"compressed", compressed, plain, false},
+		{"plain", plain, plain, false},
+		{"empty", nil, nil, false},
+		{"truncated", compressed[:len(compressed)/2], nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decompress(tt.content)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decompress() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && string(got) != string(tt.want) {
+				t.Errorf("Decompress() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
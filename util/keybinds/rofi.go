@@ -0,0 +1,20 @@
+package keybinds
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Write the binds of c one per line as `keys<TAB>action`, ready to be piped into `rofi -dmenu`
+// The action is the dispatcher followed by its arguments. Tabs inside them are replaced
+// with spaces, so every line has exactly two fields
+func WriteRofi(w io.Writer, c Config) error {
+	for _, kb := range c.Keybinds {
+		action := strings.ReplaceAll(kb.Action(), "\t", " ")
+		if _, err := fmt.Fprintf(w, "%s\t%s\n", strings.ReplaceAll(kb.Keys(), "\t", " "), action); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package keybinds
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// A windowrule= or windowrulev2= line of the config, printed by --rules
+type WindowRule struct {
+	Keyword    string `json:"keyword"` // windowrule or windowrulev2
+	Rule       string `json:"rule"`    // what is done to the windows, e.g. float or opacity 0.8
+	Match      string `json:"match"`   // which windows, a regex for windowrule and comma separated criteria like class:^(kitty)$ for windowrulev2
+	SourceFile string `json:"file"`
+	LineNumber int    `json:"line"`
+}
+
+// Split the value of a windowrule line into its rule and the windows it matches
+// The rule is everything up to the first comma, the match is left as written
+func parseWindowRule(keyword string, value string) WindowRule {
+	fields := splitBindFields(value, 2)
+	return WindowRule{Keyword: keyword, Rule: fields[0], Match: fields[1]}
+}
+
+// Replace the variables used in the rules with their values, variables should be resolved with ResolveVariables
+func SubstituteRuleVariables(rules []WindowRule, variables map[string]string) {
+	replacer := variableReplacer(variables)
+	for i := range rules {
+		rules[i].Rule = replacer.Replace(rules[i].Rule)
+		rules[i].Match = replacer.Replace(rules[i].Match)
+	}
+}
+
+// Write the window rules as a column aligned table like WritePlain, header included unless opts.NoHeader is set
+func WriteRules(w io.Writer, rules []WindowRule, opts TableOptions) error {
+	rows := [][]string{{"RULE", "MATCH", "KEYWORD"}}
+	for _, rule := range rules {
+		rows = append(rows, []string{rule.Rule, rule.Match, rule.Keyword})
+	}
+	if opts.NoHeader {
+		rows = rows[1:]
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	widths := columnWidths(rows)
+	for _, row := range rows {
+		var line strings.Builder
+		for j, cell := range row {
+			if j > 0 {
+				line.WriteString(strings.Repeat(" ", plainPadding))
+			}
+			line.WriteString(cell + strings.Repeat(" ", widths[j]-utf8.RuneCountInString(cell)))
+		}
+		if _, err := io.WriteString(w, strings.TrimRight(line.String(), " ")+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Return the window rules as a markdown table, header included unless opts.NoHeader is set
+func MarkdownRules(rules []WindowRule, opts TableOptions) []string {
+	markdown := markdownHeader([]string{"Rule", "Match", "Keyword"}, opts)
+	for _, rule := range rules {
+		markdown = append(markdown, markdownRow([]string{rule.Rule, "<code>" + rule.Match + "</code>", rule.Keyword}))
+	}
+	return markdown
+}
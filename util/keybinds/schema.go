@@ -0,0 +1,44 @@
+package keybinds
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// Return a JSON Schema describing the --json output, an array of binds
+// It is built from the JSON tags of Keybind, so it can't drift from what --json prints
+func Schema() ([]byte, error) {
+	properties := make(map[string]interface{})
+	required := []string{}
+	t := reflect.TypeOf(Keybind{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" || name == "" {
+			continue
+		}
+		kind := "string"
+		switch field.Type.Kind() {
+		case reflect.Int, reflect.Int64:
+			kind = "integer"
+		case reflect.Bool:
+			kind = "boolean"
+		}
+		properties[name] = map[string]string{"type": kind}
+		required = append(required, name)
+	}
+
+	schema := map[string]interface{}{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title":   "hyprkeys --json output",
+		"type":    "array",
+		"items": map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             required,
+			"additionalProperties": false,
+		},
+	}
+	return json.MarshalIndent(schema, "", "  ")
+}
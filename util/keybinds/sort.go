@@ -0,0 +1,47 @@
+package keybinds
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Orders Sort accepts, each falls back to the others to order binds that compare equal
+var sortOrders = map[string][]func(a, b Keybind) int{
+	"key":        {compareKeys, compareMods},
+	"mod":        {compareMods, compareKeys},
+	"dispatcher": {compareDispatchers, compareMods, compareKeys},
+}
+
+// Sort the binds by key, mod or dispatcher
+// The sort is stable, so binds that compare equal stay in the order they appear in the config
+func Sort(keybinds []Keybind, by string) error {
+	compares, ok := sortOrders[by]
+	if !ok {
+		return fmt.Errorf("can't sort by %q, expected key, mod or dispatcher", by)
+	}
+	sort.SliceStable(keybinds, func(i, j int) bool {
+		for _, compare := range compares {
+			if c := compare(keybinds[i], keybinds[j]); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	return nil
+}
+
+// Compare keys by name regardless of case
+func compareKeys(a, b Keybind) int {
+	return strings.Compare(strings.ToLower(KeyName(a.Key)), strings.ToLower(KeyName(b.Key)))
+}
+
+// Compare modifiers by their canonical names, binds without modifiers come first
+func compareMods(a, b Keybind) int {
+	return strings.Compare(strings.ToUpper(NormalizeMods(a.Mods)), strings.ToUpper(NormalizeMods(b.Mods)))
+}
+
+// Compare dispatchers regardless of case
+func compareDispatchers(a, b Keybind) int {
+	return strings.Compare(strings.ToLower(a.Dispatcher), strings.ToLower(b.Dispatcher))
+}
@@ -0,0 +1,90 @@
+package keybinds
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Aggregate counts describing a config, printed by --stats
+// The JSON field names make up the --stats --json output, keep them stable
+type Stats struct {
+	Binds       int            `json:"binds"`
+	Modifiers   map[string]int `json:"modifiers"`   // canonical modifier name -> binds using it
+	Dispatchers map[string]int `json:"dispatchers"` // dispatcher -> binds calling it
+	Variables   int            `json:"variables"`
+	Submaps     int            `json:"submaps"`
+}
+
+// Count the binds of c by modifier and dispatcher
+// Variables are resolved first, so binds using $mainMod count towards the modifier it stands for
+func NewStats(c Config) Stats {
+	stats := Stats{
+		Binds:       len(c.Keybinds),
+		Modifiers:   make(map[string]int),
+		Dispatchers: make(map[string]int),
+		Variables:   len(c.Variables),
+	}
+
+	resolved := make([]Keybind, len(c.Keybinds))
+	copy(resolved, c.Keybinds)
+	SubstituteVariables(resolved, resolvedVariables(c.Variables))
+
+	submaps := make(map[string]bool)
+	for _, kb := range resolved {
+		for _, mod := range strings.Fields(strings.ToUpper(NormalizeMods(kb.Mods))) {
+			stats.Modifiers[mod]++
+		}
+		stats.Dispatchers[kb.Dispatcher]++
+		if kb.Submap != "" {
+			submaps[kb.Submap] = true
+		}
+	}
+	stats.Submaps = len(submaps)
+	return stats
+}
+
+// Write the stats for reading in a terminal, most used modifiers and dispatchers first
+func WriteStats(w io.Writer, s Stats) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "Binds:\t%d\n", s.Binds)
+	fmt.Fprintf(tw, "Variables:\t%d\n", s.Variables)
+	fmt.Fprintf(tw, "Submaps:\t%d\n", s.Submaps)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, section := range []struct {
+		title  string
+		counts map[string]int
+	}{{"Modifiers", s.Modifiers}, {"Dispatchers", s.Dispatchers}} {
+		fmt.Fprintf(w, "\n%s:\n", section.title)
+		if len(section.counts) == 0 {
+			fmt.Fprintln(w, "  none")
+		}
+		for _, name := range byCount(section.counts) {
+			fmt.Fprintf(tw, "  %s\t%d\n", name, section.counts[name])
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Return the names in counts, highest count first and alphabetically among equal counts
+func byCount(counts map[string]int) []string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
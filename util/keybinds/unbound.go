@@ -0,0 +1,73 @@
+package keybinds
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Keys --only-unbound looks for free combinations of when no others are given
+var DefaultUnboundKeys = []string{"A-Z", "0-9"}
+
+// Modifiers --only-unbound combines the keys with when no others are given
+var DefaultUnboundMods = []string{"SUPER"}
+
+// Return the keys, with ranges expanded
+// A range is either of single characters, like a-z or 0-9, or of numbered keys, like F1-F12
+func ExpandKeys(keys []string) ([]string, error) {
+	var expanded []string
+	for _, key := range keys {
+		from, to, ok := strings.Cut(key, "-")
+		// A lone - is the minus key
+		if !ok || from == "" || to == "" {
+			expanded = append(expanded, key)
+			continue
+		}
+		if len(from) == 1 && len(to) == 1 {
+			if from[0] > to[0] {
+				return nil, fmt.Errorf("invalid key range %q, %s comes after %s", key, from, to)
+			}
+			for c := from[0]; c <= to[0]; c++ {
+				expanded = append(expanded, string(c))
+			}
+			continue
+		}
+		prefix := strings.TrimRight(from, "0123456789")
+		first, err := strconv.Atoi(from[len(prefix):])
+		if err != nil || !strings.HasPrefix(to, prefix) {
+			return nil, fmt.Errorf("invalid key range %q, expected one like a-z or F1-F12", key)
+		}
+		last, err := strconv.Atoi(to[len(prefix):])
+		if err != nil || first > last {
+			return nil, fmt.Errorf("invalid key range %q, expected one like a-z or F1-F12", key)
+		}
+		for n := first; n <= last; n++ {
+			expanded = append(expanded, prefix+strconv.Itoa(n))
+		}
+	}
+	return expanded, nil
+}
+
+// Return a bind for every combination of one of the modifier sets, like "SUPER SHIFT", with one of the keys
+// that none of the binds outside of submaps is triggered by, compared like conflicts are, see ComboKey
+// The binds should have their variables substituted first. The combinations are in the order of the sets, then the keys
+func Unbound(keybinds []Keybind, modSets []string, keys []string) []Keybind {
+	bound := make(map[string]bool)
+	for _, kb := range keybinds {
+		if kb.Submap == "" {
+			bound[ComboKey(kb)] = true
+		}
+	}
+
+	var unbound []Keybind
+	for _, mods := range modSets {
+		for _, key := range keys {
+			kb := Keybind{Key: key}
+			kb.SetMods(strings.ToUpper(NormalizeMods(mods)))
+			if !bound[ComboKey(kb)] {
+				unbound = append(unbound, kb)
+			}
+		}
+	}
+	return unbound
+}
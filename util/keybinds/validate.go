@@ -0,0 +1,106 @@
+package keybinds
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Matches a variable reference like $mainMod or ${mainMod}, the name is the first or second group
+var variableRegexp = regexp.MustCompile(`\$(?:\{([A-Za-z_][A-Za-z0-9_]*)\}|([A-Za-z_][A-Za-z0-9_]*))`)
+
+// Dispatchers whose arguments are shell commands, where $NAME is usually an environment variable
+var shellDispatchers = map[string]bool{"exec": true, "execr": true}
+
+// Return true if the arguments of dispatcher are a shell command, dispatchers are compared case-insensitively
+func isShellDispatcher(dispatcher string) bool {
+	return shellDispatchers[strings.ToLower(dispatcher)]
+}
+
+// A problem Validate found in a config
+type Problem struct {
+	File    string // file the problem is in, empty for a config given to Parse
+	Line    int    // line number, starting at 1
+	Message string
+}
+
+func (p Problem) String() string {
+	return location(p.File, p.Line) + ": " + p.Message
+}
+
+// Return a problem for every variable a bind uses that the config doesn't define, in the order of the binds
+// In the command of exec binds, variables that are set in the environment or look like environment variables
+// are left to the shell, see isShellVariable
+func UndefinedVariables(c Config) []Problem {
+	var problems []Problem
+	variables := resolvedVariables(c.Variables)
+	for _, kb := range c.Keybinds {
+		fields := []string{kb.Mods, kb.Key, kb.Dispatcher, kb.Args}
+		for i, field := range fields {
+			command := i == len(fields)-1 && isShellDispatcher(kb.Dispatcher)
+			for _, match := range variableRegexp.FindAllStringSubmatch(field, -1) {
+				name := "$" + match[1] + match[2]
+				if _, ok := variables[name]; ok || (command && isShellVariable(match[1]+match[2])) {
+					continue
+				}
+				problems = append(problems, Problem{File: kb.SourceFile, Line: kb.LineNumber, Message: "undefined variable " + name})
+			}
+		}
+	}
+	return problems
+}
+
+// Return true if name, without its $, is set in the environment or is written like environment variables are,
+// in capitals like HOME or XDG_CONFIG_HOME
+func isShellVariable(name string) bool {
+	if _, ok := os.LookupEnv(name); ok {
+		return true
+	}
+	return strings.ToUpper(name) == name
+}
+
+// Return the problems of a config, in the order of the files and lines they are on:
+// bind lines that couldn't be parsed, variables referencing each other in a cycle,
+// binds using variables that aren't defined and key combinations bound more than once
+func Validate(c Config) []Problem {
+	var problems []Problem
+	for _, err := range c.Errors {
+		problems = append(problems, Problem{File: err.File, Line: err.Line, Message: fmt.Sprintf("%v: %s", err.Reason, err.Text)})
+	}
+
+	variables, cycles := ResolveVariables(c.Variables)
+	for _, err := range cycles {
+		problem := Problem{Message: err.Error()}
+		if cycle, ok := err.(CycleError); ok {
+			problem.File, problem.Line = c.Defined[cycle.Name].File, c.Defined[cycle.Name].Line
+		}
+		problems = append(problems, problem)
+	}
+
+	problems = append(problems, UndefinedVariables(c)...)
+
+	resolved := make([]Keybind, len(c.Keybinds))
+	copy(resolved, c.Keybinds)
+	SubstituteVariables(resolved, variables)
+
+	for _, group := range FindConflicts(resolved) {
+		for _, kb := range group[1:] {
+			message := fmt.Sprintf("%s is already bound at %s", kb.KeysInSubmap(), group[0].Location())
+			problems = append(problems, Problem{File: kb.SourceFile, Line: kb.LineNumber, Message: message})
+		}
+	}
+
+	files := make(map[string]int)
+	for i, file := range c.Files {
+		files[file] = i
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].File != problems[j].File {
+			return files[problems[i].File] < files[problems[j].File]
+		}
+		return problems[i].Line < problems[j].Line
+	})
+	return problems
+}
@@ -0,0 +1,64 @@
+package keybinds
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUndefinedVariables(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"defined", "$mod = SUPER\nbind = $mod, Q, killactive\n", nil},
+		{"dangling reference", "$mod = SUPER\nbind = $mod, Q, killactive\nbind = $mainMod, W, killactive\n", []string{"line 3: undefined variable $mainMod"}},
+		{"braced", "bind = ${mod}, Q, killactive\n", []string{"line 1: undefined variable $mod"}},
+		{"every field", "bind = $a, $b, $c, $d\n", []string{"line 1: undefined variable $a", "line 1: undefined variable $b", "line 1: undefined variable $c", "line 1: undefined variable $d"}},
+		{"environment variables in exec commands", "bind = SUPER, T, exec, $TERMINAL\nbind = SUPER, F, exec, ${XDG_CONFIG_HOME}/files\nbind = SUPER, H, execr, cd $HOME\n", nil},
+		{"config variables in exec commands", "bind = SUPER, T, exec, $terminal\n", []string{"line 1: undefined variable $terminal"}},
+		{"set in the environment", "bind = SUPER, T, exec, $hyprkeys_test_terminal\n", nil},
+		{"defined after the bind", "bind = $mod, Q, killactive\n$mod = SUPER\n", nil},
+		{"defined by another variable", "$a = SUPER\n$b = $a SHIFT\nbind = $b, Q, killactive\n", nil},
+	}
+	t.Setenv("hyprkeys_test_terminal", "kitty")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, problem := range UndefinedVariables(mustParse(t, tt.content)) {
+				got = append(got, problem.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateUndefinedVariables(t *testing.T) {
+	c := mustParse(t, "bind = SUPER, Q, killactive\nbind = $missing, W, killactive\n")
+	problems := Validate(c)
+	if len(problems) != 1 || problems[0].Line != 2 || problems[0].Message != "undefined variable $missing" {
+		t.Errorf("got %v, want the undefined variable on line 2", problems)
+	}
+}
+
+func TestValidateVariableCycles(t *testing.T) {
+	c := mustParse(t, "bind = SUPER, Q, killactive\n$a = $a\n$b = 1\n$b = $b\n")
+	var got []string
+	for _, problem := range Validate(c) {
+		got = append(got, problem.String())
+	}
+	want := []string{"line 2: variable $a references itself, leaving it unresolved", "line 4: variable $b references itself, leaving it unresolved"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestValidateConflicts(t *testing.T) {
+	c := mustParse(t, "bind = SUPER, Q, killactive\nbind = super, q, exit\n")
+	problems := Validate(c)
+	if len(problems) != 1 || problems[0].String() != "line 2: super + q is already bound at line 1" {
+		t.Errorf("got %v, want the second bind as written", problems)
+	}
+}
@@ -0,0 +1,190 @@
+package keybinds
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Resolve variables that are defined in terms of other variables
+// e.g. `$mainMod = SUPER` and `$both = $mainMod SHIFT` resolve $both to "SUPER SHIFT".
+// A reference leading back to a variable that is still being resolved is a cycle,
+// it is left unexpanded instead of looping forever and returned as an error.
+// Variables are resolved in the order of their names, so a cycle resolves the same way every time
+func ResolveVariables(variableMap map[string]string) (map[string]string, []error) {
+	variables := make(map[string]string)
+	for name, value := range variableMap {
+		variables[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+
+	names := variableNames(variables)
+	resolved := make(map[string]string)
+	resolving := make(map[string]bool)
+	var cycles []error
+
+	var resolve func(name string) string
+	resolve = func(name string) string {
+		if value, ok := resolved[name]; ok {
+			return value
+		}
+		value := variables[name]
+		// Most values reference no other variable, there is no need to look for every name in them
+		if !strings.Contains(value, "$") {
+			resolved[name] = value
+			return value
+		}
+		resolving[name] = true
+		reported := make(map[string]bool)
+		var out strings.Builder
+		for i := 0; i < len(value); {
+			other, n := referenceAt(value[i:], names)
+			switch {
+			case n == 0:
+				out.WriteByte(value[i])
+				i++
+				continue
+			case resolving[other]:
+				if !reported[other] {
+					reported[other] = true
+					cycles = append(cycles, cycleError(name, other))
+				}
+				out.WriteString(value[i : i+n])
+			default:
+				out.WriteString(resolve(other))
+			}
+			i += n
+		}
+		resolving[name] = false
+		resolved[name] = out.String()
+		return resolved[name]
+	}
+
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		resolve(name)
+	}
+	return resolved, cycles
+}
+
+// Return the variables resolved with ResolveVariables, for callers that leave reporting cycles to others
+func resolvedVariables(variableMap map[string]string) map[string]string {
+	variables, _ := ResolveVariables(variableMap)
+	return variables
+}
+
+// Return the variable s starts with and how long its reference is, 0 if it doesn't start with one
+// Names are tried in the order given, longest first, like variableReplacer tries them
+func referenceAt(s string, names []string) (string, int) {
+	if !strings.HasPrefix(s, "$") {
+		return "", 0
+	}
+	for _, name := range names {
+		if strings.HasPrefix(s, name) {
+			return name, len(name)
+		}
+		if braced := bracedName(name); strings.HasPrefix(s, braced) {
+			return name, len(braced)
+		}
+	}
+	return "", 0
+}
+
+// The error for a variable left unresolved since it references Other in a cycle
+type CycleError struct {
+	Name  string
+	Other string
+}
+
+func (e CycleError) Error() string {
+	if e.Name == e.Other {
+		return fmt.Sprintf("variable %s references itself, leaving it unresolved", e.Name)
+	}
+	return fmt.Sprintf("variable %s references %s in a cycle, leaving it unresolved", e.Name, e.Other)
+}
+
+// Return the error for a variable left unresolved since it references other in a cycle
+func cycleError(name string, other string) error {
+	return CycleError{Name: name, Other: other}
+}
+
+// Return the names of the variables, longest first
+// Substituting in this order keeps $mod from being replaced inside $modShift
+func variableNames(variables map[string]string) []string {
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) != len(names[j]) {
+			return len(names[i]) > len(names[j])
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// Return the braced form of a variable name, ${mainMod} for $mainMod
+// It can be followed by letters, like ${mainMod}_L, where $mainMod_L would be another variable
+func bracedName(name string) string {
+	return "${" + strings.TrimPrefix(name, "$") + "}"
+}
+
+// Return a replacer substituting the variables, written as $name or ${name}, with their values in a single pass
+// The names are tried longest first, see variableNames
+func variableReplacer(variables map[string]string) *strings.Replacer {
+	var pairs []string
+	for _, name := range variableNames(variables) {
+		pairs = append(pairs, name, variables[name], bracedName(name), variables[name])
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+// Replace the variables used in each field of the binds with their values
+// variables should already be resolved with ResolveVariables
+func SubstituteVariables(keybinds []Keybind, variables map[string]string) {
+	replacer := variableReplacer(variables)
+	for i := range keybinds {
+		kb := &keybinds[i]
+		kb.SetMods(replacer.Replace(kb.Mods))
+		kb.Key = replacer.Replace(kb.Key)
+		kb.Dispatcher = replacer.Replace(kb.Dispatcher)
+		kb.Args = replacer.Replace(kb.Args)
+	}
+}
+
+// Matches an environment variable in a command, written as $NAME or ${NAME}
+var envRegexp = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}|\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// Matches a ~ starting a word of a command, like the shell expands it
+var homeRegexp = regexp.MustCompile(`(^|[\s=:])~(/|\s|$)`)
+
+// Expand the commands of exec binds to what they run: their config variables are replaced with their values,
+// then a ~ starting a word and environment variables like $HOME or ${XDG_CONFIG_HOME}.
+// variables should already be resolved with ResolveVariables. Environment variables that aren't set are kept as written.
+// Nothing is run, so $(...) and the like are left as they are
+func ResolveExec(keybinds []Keybind, variables map[string]string) {
+	replacer := variableReplacer(variables)
+	home, hasHome := os.LookupEnv("HOME")
+	for i := range keybinds {
+		kb := &keybinds[i]
+		if !isShellDispatcher(kb.Dispatcher) {
+			continue
+		}
+		command := replacer.Replace(kb.Args)
+		if hasHome {
+			command = homeRegexp.ReplaceAllStringFunc(command, func(match string) string {
+				return strings.Replace(match, "~", home, 1)
+			})
+		}
+		kb.Args = envRegexp.ReplaceAllStringFunc(command, func(match string) string {
+			name := strings.Trim(match, "${}")
+			if value, ok := os.LookupEnv(name); ok {
+				return value
+			}
+			return match
+		})
+	}
+}
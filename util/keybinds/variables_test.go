@@ -0,0 +1,178 @@
+package keybinds
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSubstituteVariablesLongestFirst(t *testing.T) {
+	variables := map[string]string{"$mod": "SUPER", "$modShift": "SUPER SHIFT", "$m": "ALT"}
+	tests := []struct {
+		mods string
+		want string
+	}{
+		{"$mod", "SUPER"},
+		{"$modShift", "SUPER SHIFT"},
+		{"$m", "ALT"},
+		{"$m $mod", "ALT SUPER"},
+		{"$modShiftX", "SUPER SHIFTX"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mods, func(t *testing.T) {
+			keybinds := []Keybind{{Mods: tt.mods}}
+			SubstituteVariables(keybinds, variables)
+			if keybinds[0].Mods != tt.want {
+				t.Errorf("got %q, want %q", keybinds[0].Mods, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubstituteVariablesEachField(t *testing.T) {
+	config := "$mod = SUPER\n$key = R\n$menu = wofi --show drun\n$term = kitty | tee\n"
+	tests := []struct {
+		line       string
+		mods       string
+		key        string
+		dispatcher string
+		args       string
+	}{
+		{"bind = $mod, $key, exec, $menu", "SUPER", "R", "exec", "wofi --show drun"},
+		{"bind = $mod SHIFT, Q, exec, $term", "SUPER SHIFT", "Q", "exec", "kitty | tee"},
+		{"bind = , $key, exec, echo $undefined", "", "R", "exec", "echo $undefined"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			c := mustParse(t, config+tt.line+"\n")
+			SubstituteVariables(c.Keybinds, resolvedVariables(c.Variables))
+			kb := c.Keybinds[0]
+			if kb.Mods != tt.mods || kb.Key != tt.key || kb.Dispatcher != tt.dispatcher || kb.Args != tt.args {
+				t.Errorf("got %q, %q, %q, %q, want %q, %q, %q, %q", kb.Mods, kb.Key, kb.Dispatcher, kb.Args, tt.mods, tt.key, tt.dispatcher, tt.args)
+			}
+		})
+	}
+}
+
+// Return a config of n binds using some of the variables it defines
+func syntheticConfig(n int) string {
+	var b strings.Builder
+	const variables = 50
+	for i := 0; i < variables; i++ {
+		fmt.Fprintf(&b, "$var%d = value%d\n", i, i)
+	}
+	b.WriteString("$mod = SUPER\n$modShift = $mod SHIFT\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "bind = $modShift, K%d, exec, run --var $var%d ${var%d}_x # bind %d\n", i, i%variables, (i+1)%variables, i)
+	}
+	return b.String()
+}
+
+func BenchmarkSubstituteVariables(b *testing.B) {
+	c, err := Parse([]byte(syntheticConfig(5000)))
+	if err != nil {
+		b.Fatal(err)
+	}
+	variables := resolvedVariables(c.Variables)
+	// Replacing one variable at a time, scanning every field once per variable, to compare with
+	perVariable := func(keybinds []Keybind, variables map[string]string) {
+		names := variableNames(variables)
+		for i := range keybinds {
+			kb := &keybinds[i]
+			for _, name := range names {
+				kb.Mods = strings.ReplaceAll(kb.Mods, name, variables[name])
+				kb.Args = strings.ReplaceAll(kb.Args, bracedName(name), variables[name])
+				kb.Args = strings.ReplaceAll(kb.Args, name, variables[name])
+			}
+		}
+	}
+	for _, bb := range []struct {
+		name       string
+		substitute func([]Keybind, map[string]string)
+	}{
+		{"replacer", SubstituteVariables},
+		{"per variable", perVariable},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			keybinds := make([]Keybind, len(c.Keybinds))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				copy(keybinds, c.Keybinds)
+				bb.substitute(keybinds, variables)
+			}
+		})
+	}
+}
+
+func TestSubstituteBracedVariables(t *testing.T) {
+	variables := map[string]string{"$mod": "SUPER", "$mod_L": "ALT", "$term": "kitty", "$dir": "/home/user"}
+	tests := []struct {
+		args string
+		want string
+	}{
+		{"${term}", "kitty"},
+		{"$term -e ${dir}/run", "kitty -e /home/user/run"},
+		{"${mod}_L $mod_L", "SUPER_L ALT"},
+		{"${term}${term}", "kittykitty"},
+		{"${undefined} $undefined", "${undefined} $undefined"},
+		{"${term", "${term"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			keybinds := []Keybind{{Mods: "${mod}", Args: tt.args}}
+			SubstituteVariables(keybinds, variables)
+			if keybinds[0].Args != tt.want || keybinds[0].Mods != "SUPER" {
+				t.Errorf("got mods %q and args %q, want SUPER and %q", keybinds[0].Mods, keybinds[0].Args, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveBracedVariables(t *testing.T) {
+	variables, errs := ResolveVariables(map[string]string{"$mod": "SUPER", "$both": "${mod} SHIFT", "$all": "$both ${mod}_R"})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if variables["$both"] != "SUPER SHIFT" || variables["$all"] != "SUPER SHIFT SUPER_R" {
+		t.Errorf("got $both %q and $all %q", variables["$both"], variables["$all"])
+	}
+}
+
+func TestResolveExec(t *testing.T) {
+	t.Setenv("HOME", "/home/user")
+	t.Setenv("XDG_CONFIG_HOME", "/xdg")
+	// restored after the test by Setenv
+	t.Setenv("HYPRKEYS_UNSET", "")
+	os.Unsetenv("HYPRKEYS_UNSET")
+	variables := map[string]string{"$term": "kitty", "$scripts": "~/scripts"}
+	tests := []struct {
+		dispatcher string
+		args       string
+		want       string
+	}{
+		{"exec", "$term -e htop", "kitty -e htop"},
+		{"exec", "$HOME/bin/run", "/home/user/bin/run"},
+		{"exec", "${XDG_CONFIG_HOME}/hypr/run.sh", "/xdg/hypr/run.sh"},
+		{"exec", "~/bin/run ~", "/home/user/bin/run /home/user"},
+		{"exec", "$scripts/volume.sh", "/home/user/scripts/volume.sh"},
+		{"exec", "echo a~b ~other", "echo a~b ~other"},
+		{"exec", "$HYPRKEYS_UNSET/run", "$HYPRKEYS_UNSET/run"},
+		{"exec", "echo $(date)", "echo $(date)"},
+		{"execr", "$term", "kitty"},
+		{"EXEC", "$term", "kitty"},
+		{"workspace", "$HOME", "$HOME"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dispatcher+" "+tt.args, func(t *testing.T) {
+			keybinds := []Keybind{{Mods: "$term", Dispatcher: tt.dispatcher, Args: tt.args}}
+			ResolveExec(keybinds, variables)
+			if keybinds[0].Args != tt.want {
+				t.Errorf("got %q, want %q", keybinds[0].Args, tt.want)
+			}
+			if keybinds[0].Mods != "$term" {
+				t.Errorf("changed the modifiers to %q, only commands are expanded", keybinds[0].Mods)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package keybinds
+
+import "encoding/json"
+
+// Version of the widget JSON shape, bump it whenever a change would break widgets reading it
+const WidgetVersion = 1
+
+// Compact cheatsheet printed by --widget-json, for templating into eww or waybar tooltips
+// Values are ready to display: variables are resolved and modifiers normalized.
+// Binds are grouped by submap when the config uses any, otherwise by their modifiers.
+// It is printed on a single line:
+//
+//	{"version":1,"groups":[{"name":"SUPER","binds":[{"keys":"SUPER + Q","action":"exec kitty","desc":"terminal"}]}]}
+type Widget struct {
+	Version int           `json:"version"`
+	Groups  []WidgetGroup `json:"groups"`
+}
+
+// A group of binds of the widget cheatsheet, in the order its first bind appears in the config
+type WidgetGroup struct {
+	Name  string       `json:"name"`
+	Binds []WidgetBind `json:"binds"`
+}
+
+// A bind of the widget cheatsheet
+type WidgetBind struct {
+	Keys   string `json:"keys"`           // e.g. "SUPER + Q"
+	Action string `json:"action"`         // dispatcher followed by its arguments
+	Desc   string `json:"desc,omitempty"` // description from bindd=, or the comment with --comments
+}
+
+// Build the widget cheatsheet for the binds of c
+func NewWidget(c Config) Widget {
+	resolved := make([]Keybind, len(c.Keybinds))
+	copy(resolved, c.Keybinds)
+	SubstituteVariables(resolved, resolvedVariables(c.Variables))
+	Normalize(resolved)
+
+	name := func(kb Keybind) string {
+		if kb.Mods == "" {
+			return "No modifier"
+		}
+		return kb.Mods
+	}
+	if hasSubmaps(resolved) {
+		name = func(kb Keybind) string {
+			if kb.Submap == "" {
+				return "Global"
+			}
+			return kb.Submap
+		}
+	}
+
+	widget := Widget{Version: WidgetVersion, Groups: []WidgetGroup{}}
+	for _, group := range groupKeybinds(resolved, name) {
+		wg := WidgetGroup{Name: group.Name}
+		for _, kb := range group.Keybinds {
+			wg.Binds = append(wg.Binds, WidgetBind{Keys: kb.Keys(), Action: kb.Action(), Desc: kb.Description})
+		}
+		widget.Groups = append(widget.Groups, wg)
+	}
+	return widget
+}
+
+// Return the widget cheatsheet for the binds of c as single line JSON
+func WidgetJSON(c Config) ([]byte, error) {
+	return json.Marshal(NewWidget(c))
+}
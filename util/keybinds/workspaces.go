@@ -0,0 +1,75 @@
+package keybinds
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Dispatchers whose first argument is a workspace
+var workspaceDispatchers = map[string]bool{
+	"workspace":                      true,
+	"movetoworkspace":                true,
+	"movetoworkspacesilent":          true,
+	"focusworkspaceoncurrentmonitor": true,
+	"togglespecialworkspace":         true,
+}
+
+// Return a readable form of the command of a bind, e.g. "Workspace 1" for `workspace, 1`
+// or "Special: magic" for `movetoworkspace, special:magic`. Only the workspace argument is
+// rewritten, anything after it is kept. Commands without a better form are returned as they are
+func CommandName(dispatcher string, command string) string {
+	dispatcher = strings.ToLower(dispatcher)
+	if !workspaceDispatchers[dispatcher] {
+		return command
+	}
+
+	target, rest := command, ""
+	if i := strings.Index(command, ","); i >= 0 {
+		target, rest = command[:i], command[i:]
+	}
+	target = strings.TrimSpace(target)
+
+	// togglespecialworkspace takes the name of the special workspace, without the special: prefix
+	if dispatcher == "togglespecialworkspace" {
+		if target == "" {
+			return "Special" + rest
+		}
+		return "Special: " + target + rest
+	}
+	return WorkspaceName(target) + rest
+}
+
+// Return a readable name for a workspace as dispatchers refer to it, e.g. "Next open workspace" for e+1
+func WorkspaceName(workspace string) string {
+	switch {
+	case workspace == "":
+		return workspace
+	case workspace == "previous":
+		return "Previous workspace"
+	case workspace == "empty":
+		return "Empty workspace"
+	case workspace == "special":
+		return "Special"
+	case strings.HasPrefix(workspace, "special:"):
+		return "Special: " + strings.TrimPrefix(workspace, "special:")
+	case strings.HasPrefix(workspace, "name:"):
+		return "Workspace " + strings.TrimPrefix(workspace, "name:")
+	}
+
+	// Relative workspaces: e counts open workspaces only, m those on the current monitor
+	prefix, offset := "", workspace
+	if strings.HasPrefix(workspace, "e") || strings.HasPrefix(workspace, "m") || strings.HasPrefix(workspace, "r") {
+		prefix, offset = workspace[:1], workspace[1:]
+	}
+	if n, err := strconv.Atoi(offset); err == nil && (offset[0] == '+' || offset[0] == '-') {
+		which := map[string]string{"": "workspace", "r": "workspace", "e": "open workspace", "m": "workspace on the monitor"}[prefix]
+		switch n {
+		case 1:
+			return "Next " + which
+		case -1:
+			return "Previous " + which
+		}
+		return strings.ToUpper(which[:1]) + which[1:] + " " + offset
+	}
+	return "Workspace " + workspace
+}
@@ -0,0 +1,125 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/oleiade/reflections"
+	props "notashelf.dev/hyprkeys/util/properties"
+)
+
+// Version of the Document schema, bump it whenever a change would break consumers
+const DocumentVersion = 1
+
+// Stable JSON representation of a parsed config, printed by --blocks
+// It doesn't depend on the field names of the props structs, so they can change freely.
+//
+//	{
+//	  "version": 1,
+//	  "sections": {"general": {"gaps_in": 5, "col.active_border": "..."}, "input": {"touchpad": {...}}},
+//	  "binds": [{"keyword": "bind", "fields": ["$mainMod", "Q", "exec", "kitty"]}],
+//	  "variables": {"$mainMod": "SUPER"},
+//	  "order": ["general", "input", "touchpad"]
+//	}
+type Document struct {
+	Version   int                               `json:"version"`
+	Sections  map[string]map[string]interface{} `json:"sections"` // section name -> option name -> value, nested sections are nested objects
+	Order     []string                          `json:"order"`    // sections and nested sections in the order the config has them
+	Binds     []DocumentBind                    `json:"binds"`
+	Variables map[string]string                 `json:"variables"`
+}
+
+// A bind line of the config
+type DocumentBind struct {
+	Keyword string   `json:"keyword"`          // bind keyword including its flags, e.g. bindle
+	Fields  []string `json:"fields"`           // comma separated values after the equals sign, trimmed
+	Submap  string   `json:"submap,omitempty"` // submap the bind belongs to, left out of binds outside of one
+}
+
+// Return the option name a props field is written as in the config, e.g. S_col__active_border -> col.active_border
+func propertyName(field string) string {
+	return strings.TrimPrefix(strings.Replace(field, "__", ".", 1), "S_")
+}
+
+// Return the options of a section as a map from option name to value
+func sectionValues(section interface{}) (map[string]interface{}, error) {
+	fields, err := reflections.Fields(section)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]interface{})
+	for _, field := range fields {
+		val, err := reflections.GetField(section, field)
+		if err != nil {
+			return nil, err
+		}
+		// Nested sections like input:touchpad are structs themselves
+		if _, err := reflections.Fields(val); err == nil {
+			nested, err := sectionValues(val)
+			if err != nil {
+				return nil, err
+			}
+			values[propertyName(field)] = nested
+			continue
+		}
+		values[propertyName(field)] = val
+	}
+	return values, nil
+}
+
+// Build the Document for a parsed config
+func NewDocument(conf props.Config) (Document, error) {
+	doc := Document{
+		Version:   DocumentVersion,
+		Sections:  make(map[string]map[string]interface{}),
+		Binds:     []DocumentBind{},
+		Variables: make(map[string]string),
+		Order:     append([]string{}, conf.Global.S_order...),
+	}
+
+	fields, err := reflections.Fields(conf)
+	if err != nil {
+		return Document{}, fmt.Errorf("getting the sections: %w", err)
+	}
+	for _, field := range fields {
+		if field == "Global" {
+			continue
+		}
+		block, err := reflections.GetField(conf, field)
+		if err != nil {
+			return Document{}, fmt.Errorf("getting section %s: %w", field, err)
+		}
+		values, err := sectionValues(block)
+		if err != nil {
+			return Document{}, fmt.Errorf("getting the options of section %s: %w", field, err)
+		}
+		doc.Sections[strings.ToLower(field)] = values
+	}
+
+	submap := ""
+	for _, binds := range conf.Global.S_binds {
+		for keyword, vals := range binds {
+			// submap= lines are kept among the binds, they set the submap of the binds that follow
+			if keyword == "submap" {
+				submap = strings.TrimSpace(strings.Join(vals, ","))
+				if submap == "reset" {
+					submap = ""
+				}
+				continue
+			}
+			if keyword == CommentKeyword {
+				continue
+			}
+			bind := DocumentBind{Keyword: keyword, Fields: make([]string, len(vals)), Submap: submap}
+			for i, val := range vals {
+				bind.Fields[i] = strings.TrimSpace(val)
+			}
+			doc.Binds = append(doc.Binds, bind)
+		}
+	}
+	for name, value := range conf.Global.S_variables {
+		doc.Variables[name] = value
+	}
+
+	return doc, nil
+}
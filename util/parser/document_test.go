@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestNewDocument(t *testing.T) {
+	conf := mustParse(t, "$mod = SUPER\ngeneral {\n    gaps_in = 3\n    col.active_border = rgb(ffffff)\n}\ninput {\n    touchpad {\n        natural_scroll = yes\n    }\n}\nbind = $mod, Q, exec, kitty\nsubmap = resize\nbinde = , right, resizeactive, 10 0\nsubmap = reset\n")
+	doc, err := NewDocument(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.Version != DocumentVersion {
+		t.Errorf("version %d, want %d", doc.Version, DocumentVersion)
+	}
+	if got, want := doc.Order, []string{"general", "input", "touchpad"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("order %q, want %q", got, want)
+	}
+	if got := doc.Sections["general"]["gaps_in"]; got != int64(3) {
+		t.Errorf("general.gaps_in = %#v, want 3", got)
+	}
+	if got := doc.Sections["general"]["col.active_border"]; got != "rgb(ffffff)" {
+		t.Errorf("general.col.active_border = %#v, want rgb(ffffff)", got)
+	}
+	touchpad, ok := doc.Sections["input"]["touchpad"].(map[string]interface{})
+	if !ok || touchpad["natural_scroll"] != true {
+		t.Errorf("input.touchpad = %#v, want natural_scroll true", doc.Sections["input"]["touchpad"])
+	}
+	wantBinds := []DocumentBind{
+		{Keyword: "bind", Fields: []string{"$mod", "Q", "exec", "kitty"}},
+		{Keyword: "binde", Fields: []string{"", "right", "resizeactive", "10 0"}, Submap: "resize"},
+	}
+	if !reflect.DeepEqual(doc.Binds, wantBinds) {
+		t.Errorf("binds %+v, want %+v", doc.Binds, wantBinds)
+	}
+	if got, want := doc.Variables, map[string]string{"$mod": "SUPER"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("variables %q, want %q", got, want)
+	}
+}
+
+func TestDocumentJSONFields(t *testing.T) {
+	doc, err := NewDocument(mustParse(t, "bind = SUPER, Q, exec, kitty\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"version", "sections", "order", "binds", "variables"} {
+		if _, ok := fields[name]; !ok {
+			t.Errorf("the JSON has no %q field: %s", name, data)
+		}
+	}
+	if len(fields) != 5 {
+		t.Errorf("the JSON has %d fields, want 5: %s", len(fields), data)
+	}
+}
@@ -0,0 +1,126 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	keybinds "notashelf.dev/hyprkeys/util/keybinds"
+)
+
+// A problem Parse found in a config, the config is parsed as well as it can be regardless
+type ParseError struct {
+	Line    int    // line number, starting at 1, 0 when the line isn't known
+	Column  int    // column the problem starts at, starting at 1
+	Text    string // the line as written
+	Message string
+}
+
+func (e ParseError) Error() string {
+	if e.Line == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("line %d, column %d: %s: %s", e.Line, e.Column, e.Message, strings.TrimSpace(e.Text))
+}
+
+// Where the options and sections of a config are, see findPositions
+type positions map[string]position
+
+// Where an option or section is written in the config
+type position struct {
+	line        int
+	keyColumn   int // column of its name
+	valueColumn int // column of its value, after the equals sign
+	text        string
+}
+
+// Return an error for the option or section at key in the positions, see CommentKey
+// Keys that aren't in the positions give an error without a line
+func (p positions) errorAt(key string, atValue bool, message string) ParseError {
+	pos, ok := p[key]
+	if !ok {
+		return ParseError{Message: message}
+	}
+	column := pos.keyColumn
+	if atValue {
+		column = pos.valueColumn
+	}
+	return ParseError{Line: pos.line, Column: column, Text: pos.text, Message: message}
+}
+
+// Return an error for the section label, where it is first written
+func (p positions) sectionError(label string, message string) ParseError {
+	suffix := "." + sectionMarker + label
+	first := ""
+	for key, pos := range p {
+		if strings.HasSuffix(key, suffix) && (first == "" || pos.line < p[first].line) {
+			first = key
+		}
+	}
+	if first == "" {
+		return ParseError{Message: message}
+	}
+	return p.errorAt(first, false, message)
+}
+
+// Return where each option and section of content is, by CommentKey of the section it is written in and its name,
+// sections by "{" and their label in the section they are written in. An option set twice is where it is last set,
+// a section opened twice where it is first opened.
+// A } closing no section and sections that are never closed are returned as errors
+func findPositions(content string) (positions, []ParseError) {
+	found := make(positions)
+	var errs []ParseError
+	type open struct {
+		label  string
+		line   int
+		column int
+		text   string
+	}
+	var sections []open
+	section := func() string {
+		if len(sections) == 0 {
+			return "global"
+		}
+		return sections[len(sections)-1].label
+	}
+
+	for i, line := range strings.Split(content, "\n") {
+		// Comments are cut off like markComments does
+		code := line
+		if j := keybinds.CommentIndex(code); j >= 0 {
+			code = code[:j]
+		}
+		column := func(j int) int { return utf8.RuneCountInString(line[:j]) + 1 }
+		keyColumn := column(len(code) - len(strings.TrimLeft(code, " \t")))
+
+		for j := 0; j < len(code); j++ {
+			switch code[j] {
+			case '{':
+				label := GetLabel(j, code)
+				if key := CommentKey(section(), sectionMarker+label); found[key].line == 0 {
+					found[key] = position{line: i + 1, keyColumn: keyColumn, valueColumn: column(j), text: line}
+				}
+				sections = append(sections, open{label: label, line: i + 1, column: column(j), text: line})
+			case '}':
+				if len(sections) == 0 {
+					errs = append(errs, ParseError{Line: i + 1, Column: column(j), Text: line, Message: "} closes no section"})
+					continue
+				}
+				sections = sections[:len(sections)-1]
+			}
+		}
+
+		if strings.ContainsAny(code, "{}") {
+			continue
+		}
+		if name, value, ok := strings.Cut(code, "="); ok {
+			valueStart := len(name) + 1 + len(value) - len(strings.TrimLeft(value, " \t"))
+			found[CommentKey(section(), strings.TrimSpace(name))] = position{line: i + 1, keyColumn: keyColumn, valueColumn: column(valueStart), text: line}
+		}
+	}
+
+	for _, s := range sections {
+		errs = append(errs, ParseError{Line: s.line, Column: s.column, Text: s.text, Message: "section " + s.label + " is never closed"})
+	}
+	return found, errs
+}
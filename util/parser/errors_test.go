@@ -0,0 +1,14 @@
+package parser
+
+import "testing"
+
+func TestSectionErrorFirstLine(t *testing.T) {
+	content := "general {\n    gaps_in = 5\n}\ninput {\n    {\n    }\n}\n{\n}\n"
+	// Go randomizes map iteration, the line must not depend on it
+	for i := 0; i < 20; i++ {
+		_, errs := Parse(content)
+		if len(errs) != 1 || errs[0].Line != 5 || errs[0].Message != "section without a label" {
+			t.Fatalf("got %v, want the section without a label on line 5", errs)
+		}
+	}
+}
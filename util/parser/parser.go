@@ -2,10 +2,13 @@ package parser
 
 import (
 	"fmt"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/oleiade/reflections"
+	keybinds "notashelf.dev/hyprkeys/util/keybinds"
 	props "notashelf.dev/hyprkeys/util/properties"
 )
 
@@ -52,8 +55,11 @@ func TrimBlock(block string) string {
 	return out
 }
 
-func ParseBlocks(content string) map[string]string {
+// Split the content into blocks by label, and return the labels in the order their blocks open
+// Content outside of any block is the "global" block, which comes first
+func ParseBlocks(content string) (map[string]string, []string) {
 	blocks := make(map[string]string)
+	order := []string{"global"}
 	depth := []string{""} // keeps track of the blocks currently in scope and their depths(index)
 
 	labels := []string{}
@@ -82,9 +88,18 @@ func ParseBlocks(content string) map[string]string {
 			}
 		}
 		if letter == "{" {
-			depth_label = append(depth_label, GetLabel(i, content))
+			label := GetLabel(i, content)
+			if !contains(order, label) {
+				order = append(order, label)
+			}
+			// mark where the block was, so the comments above it stay above it
+			depth[len(depth)-1] += "\n" + sectionMarker + label + "\n"
+			depth_label = append(depth_label, label)
 			label_buffer = ""
 			depth = append(depth, "")
+		} else if letter == "}" && len(depth_label) == 0 {
+			// closes no block, Parse reports it
+			continue
 		} else if letter == "}" {
 			// move block from `depth` to `blocks` as it goes out of scope now
 			blocks[depth_label[len(depth_label)-1]] = TrimBlock(depth[len(depth)-1])
@@ -103,9 +118,25 @@ func ParseBlocks(content string) map[string]string {
 
 	}
 
+	// blocks that are never closed end with the content, Parse reports them
+	for len(depth_label) > 0 {
+		blocks[depth_label[len(depth_label)-1]] = TrimBlock(depth[len(depth)-1])
+		depth_label = RemoveIndex(depth_label, len(depth_label)-1)
+		depth = RemoveIndex(depth, len(depth)-1)
+	}
+
 	// set the global block
 	blocks["global"] = TrimBlock(depth[0])
-	return blocks
+	return blocks, order
+}
+
+func contains(s []string, word string) bool {
+	for _, w := range s {
+		if w == word {
+			return true
+		}
+	}
+	return false
 }
 
 func ParseComments(content string) string {
@@ -123,209 +154,785 @@ func ParseComments(content string) string {
 	return TrimBlock(out)
 }
 
+// Comments and blank lines survive parsing as marker lines, so BuildConf can write them back
+// A comment line is kept as commentMarker followed by the line, a blank line as the marker alone.
+// Braces are escaped, so ParseBlocks never mistakes a comment for the start or end of a block
+const commentMarker = "#"
+
+// Marker of the line markComments adds after every other line, followed by the line as written, see S_text
+// The line it follows is cut off before its comment. Sections have the text of the line opening them
+// as the first line of their block, and the text of the line closing them after where they were
+const textMarker = commentMarker + "="
+
+// Line ParseBlocks leaves in the parent block where a block was, followed by the label of the block
+const sectionMarker = "{"
+
+// Keyword of the S_binds entries holding the comment lines written above the next bind
+const CommentKeyword = "#"
+
+var (
+	commentEscaper   = strings.NewReplacer("%", "%25", "{", "%7B", "}", "%7D")
+	blankEscaper     = strings.NewReplacer(" ", "%20", "\t", "%09")
+	commentUnescaper = strings.NewReplacer("%25", "%", "%7B", "{", "%7D", "}", "%20", " ", "%09", "\t")
+)
+
+// Return a line escaped for a marker line, its braces and the blanks it ends with, which lines of blocks are trimmed of
+func escapeMarker(line string) string {
+	escaped := commentEscaper.Replace(line)
+	code := strings.TrimRight(escaped, " \t")
+	return code + blankEscaper.Replace(escaped[len(code):])
+}
+
+// Return the key of the comments above name in the section label, e.g. input.kb_layout
+// Sections are named in the section they are written in, e.g. global.input, and "}" is the end of the section
+func CommentKey(label string, name string) string {
+	return label + "." + name
+}
+
+// Return the comment line or blank line a marker line stands for, if line is one
+func commentLine(line string) (string, bool) {
+	if !strings.HasPrefix(line, commentMarker) || strings.HasPrefix(line, textMarker) {
+		return "", false
+	}
+	return commentUnescaper.Replace(strings.TrimPrefix(line, commentMarker)), true
+}
+
+// Return the line as written a text marker line holds, if line is one, see textMarker
+func textLine(line string) (string, bool) {
+	if !strings.HasPrefix(line, textMarker) {
+		return "", false
+	}
+	return commentUnescaper.Replace(strings.TrimPrefix(line, textMarker)), true
+}
+
+// Replace comment lines and blank lines with marker lines, see commentMarker, and follow every other line with its text
+// Those lines are cut off before their comment, found like keybinds.CommentIndex finds it, and trimmed for parsing.
+// Lines of nothing but blanks are blank lines
+func markComments(content string) string {
+	if content == "" {
+		return ""
+	}
+	var out strings.Builder
+	for _, line := range strings.Split(strings.TrimSuffix(content, "\n"), "\n") {
+		code := strings.TrimSpace(line)
+		switch {
+		case code == "":
+			out.WriteString(commentMarker + "\n")
+		case strings.HasPrefix(code, "#"):
+			out.WriteString(commentMarker + escapeMarker(line) + "\n")
+		default:
+			if i := keybinds.CommentIndex(code); i >= 0 {
+				code = strings.TrimRight(code[:i], " \t")
+			}
+			out.WriteString(code + "\n" + textMarker + escapeMarker(line) + "\n")
+		}
+	}
+	return out.String()
+}
+
+// Return the key of the text of the bind at index in S_binds, see S_text
+func bindTextKey(index int) string {
+	return CommentKey(layoutBind, strconv.Itoa(index))
+}
+
+// Return the name and value of an option line, the value is empty when it has none
+func optionValue(line string) (string, string) {
+	pairs := strings.Split(line, "=")
+	name := strings.Trim(pairs[0], " \n")
+	if len(pairs) != 2 {
+		return name, ""
+	}
+	return name, strings.Trim(pairs[1], " \n")
+}
+
+// Entries of the global S_layout for the next entry of S_binds and the next line of S_raw
+// Variables are in it by their name, and sections by sectionMarker followed by their label.
+// Sections have their options and keywords in it by name, and their nested sections like the global block has sections
+const (
+	layoutBind = "bind"
+	layoutRaw  = "raw"
+)
+
 func ParseGlobal(content string) *props.S_global {
-	lines := strings.Split(content, "\n")
+	lines := withoutHeader(strings.Split(content, "\n"))
 	global := props.NewGlobal()
+	// keep the comments and the line in S_raw, and return what replaces the line with its text
+	raw := func(comments string, line string) func(string) {
+		text := comments + line + "\n"
+		global.S_raw += text
+		for i := 0; i < strings.Count(text, "\n"); i++ {
+			global.S_layout["global"] = append(global.S_layout["global"], layoutRaw)
+		}
+		return func(written string) {
+			global.S_raw = strings.TrimSuffix(global.S_raw, line+"\n") + written + "\n"
+		}
+	}
+	bind := func(entry map[string][]string) {
+		global.S_binds = append(global.S_binds, entry)
+		global.S_layout["global"] = append(global.S_layout["global"], layoutBind)
+	}
+	// comment lines since the last line that wasn't one
+	var comments []string
+	// keeps the text of the last line read, from the text marker line after it
+	var setText func(text string)
 	for _, line := range lines {
 		line = strings.Trim(line, " ")
+		if text, ok := textLine(line); ok {
+			if setText != nil {
+				setText(text)
+			}
+			setText = nil
+			continue
+		}
+		if comment, ok := commentLine(line); ok {
+			comments = append(comments, comment)
+			setText = nil
+			continue
+		}
+		if strings.HasPrefix(line, sectionMarker) {
+			label := strings.TrimPrefix(line, sectionMarker)
+			global.S_comments[CommentKey("global", label)] = comments
+			global.S_layout["global"] = append(global.S_layout["global"], line)
+			comments = nil
+			// the line closing the section comes after where it was
+			setText = func(text string) { global.S_text[CommentKey(label, "}")] = text }
+			continue
+		}
 		parts := strings.SplitN(line, "=", 2)
 		if len(parts) == 2 {
-			parts[0] = strings.Trim(parts[0], " ")
-			parts[1] = strings.Trim(parts[1], " ")
+			name := strings.Trim(parts[0], " ")
+			value := strings.Trim(parts[1], " ")
 			if strings.HasPrefix(line, "$") {
-				global.S_variables[parts[0]] = parts[1]
-			} else if strings.HasPrefix(line, "bind") {
-				global.S_binds = append(global.S_binds, map[string][]string{parts[0]: strings.Split(parts[1], ",")})
+				// a variable set twice is written where it is first set, with the value it is last set to
+				if _, ok := global.S_variables[name]; !ok {
+					global.S_layout["global"] = append(global.S_layout["global"], name)
+				}
+				global.S_variables[name] = value
+				if len(comments) > 0 {
+					global.S_comments[CommentKey("global", name)] = comments
+				}
+				setText = func(text string) { global.S_text[CommentKey("global", name)] = text }
+			} else if keybinds.IsBindKeyword(name) || name == "submap" {
+				if len(comments) > 0 {
+					bind(map[string][]string{CommentKeyword: comments})
+				}
+				// submap= lines stay between the binds, so the binds they start and end keep their submap
+				bind(map[string][]string{name: strings.Split(value, ",")})
+				key := bindTextKey(len(global.S_binds) - 1)
+				setText = func(text string) { global.S_text[key] = text }
+			} else {
+				// keywords like monitor= or exec-once= aren't parsed, keep them as they are
+				setText = raw(buildComments(comments), line)
 			}
+		} else if line == "" {
+			// left where blocks were cut out, not a line of the config
+			continue
 		} else {
-			global.S_raw += line + "\n"
+			setText = raw(buildComments(comments), line)
 		}
+		comments = nil
+	}
+	if len(comments) > 0 {
+		global.S_comments[CommentKey("global", "}")] = comments
 	}
 	return global
 }
 
-func ParseConfig(blocks map[string]string) props.Config {
+// Parse the blocks into a config, in the order the labels are given in
+// The order is kept in the global block, so BuildConf writes the sections in the same order.
+// Unknown sections and options and values that don't fit their option are returned as errors, without their lines
+func ParseConfig(blocks map[string]string, order []string) (props.Config, []ParseError) {
+	return parseConfig(blocks, order, nil)
+}
+
+// Parse the blocks like ParseConfig, the errors are located with the positions of the config they were split from
+func parseConfig(blocks map[string]string, order []string, at positions) (props.Config, []ParseError) {
 	defaults := props.NewConf()
-	for rawlabel, block := range blocks {
+	var errs []ParseError
+	for _, rawlabel := range order {
+		block := blocks[rawlabel]
+		if rawlabel == "" {
+			errs = append(errs, at.sectionError(rawlabel, "section without a label"))
+			continue
+		}
+		if strings.ContainsAny(rawlabel, "{}") {
+			errs = append(errs, at.sectionError(rawlabel, "invalid section label "+rawlabel))
+			continue
+		}
+		if isDeviceSection(rawlabel) {
+			lines, open := deviceLines(block)
+			defaults.Global.S_devices[rawlabel] = lines
+			if open != "" {
+				defaults.Global.S_text[CommentKey("global", sectionMarker+rawlabel)] = open
+			}
+			continue
+		}
 		label := strings.ToUpper(string(rawlabel[0])) + rawlabel[1:]
 		if label == "Global" {
-			reflections.SetField(&defaults, label, ParseGlobal(block))
+			global := ParseGlobal(block)
+			global.S_order = order[1:]
+			reflections.SetField(&defaults, label, global)
+			// the global block is all binds, variables and keywords ParseGlobal already read
+			continue
 		}
 		var section interface{}
 		var err error
 		if label == "Touchpad" || label == "Touchdevice" {
-			section, err = reflections.GetField(defaults.Input, label)
+			section, err = reflections.GetField(defaults.Input, "S_"+rawlabel)
 		} else {
 			section, err = reflections.GetField(&defaults, label)
 		}
 		if err != nil {
-			fmt.Println("error parsing a label: "+label, err)
+			errs = append(errs, at.sectionError(rawlabel, "unknown section "+rawlabel))
 			continue
 		}
 		lines := strings.Split(block, "\n")
+		// options in the order they are written, an option set twice keeps its last value
+		var keys []string
 		keyval := make(map[string]string)
+		// comment lines since the last option
+		var comments []string
+		// keeps the text of the last line read, the first text of the block is the line opening it
+		parent := parentSection(rawlabel)
+		setText := func(text string) { defaults.Global.S_text[CommentKey(parent, sectionMarker+rawlabel)] = text }
 		for _, i := range lines {
-			pairs := strings.Split(i, "=")
-			pairs[0] = strings.Trim(pairs[0], " \n")
-			if len(pairs) == 2 {
-				pairs[1] = strings.Trim(pairs[1], " \n")
-				keyval[pairs[0]] = pairs[1]
-			} else {
-				keyval[pairs[0]] = ""
+			if text, ok := textLine(strings.TrimSpace(i)); ok {
+				if setText != nil {
+					setText(text)
+				}
+				setText = nil
+				continue
+			}
+			if comment, ok := commentLine(strings.TrimSpace(i)); ok {
+				comments = append(comments, comment)
+				setText = nil
+				continue
+			}
+			if nested := strings.TrimSpace(i); strings.HasPrefix(nested, sectionMarker) {
+				label := strings.TrimPrefix(nested, sectionMarker)
+				if len(comments) > 0 {
+					defaults.Global.S_comments[CommentKey(rawlabel, label)] = comments
+				}
+				defaults.Global.S_layout[rawlabel] = append(defaults.Global.S_layout[rawlabel], nested)
+				comments = nil
+				setText = func(text string) { defaults.Global.S_text[CommentKey(label, "}")] = text }
+				continue
 			}
+			name, value := optionValue(i)
+			// blank lines aren't options
+			if name == "" {
+				continue
+			}
+			if sectionKeywords[name] {
+				// given once per line, they aren't options and keep their comments among them
+				keywords := append(defaults.Global.S_keywords[rawlabel], append(comments, strings.TrimSpace(i))...)
+				defaults.Global.S_keywords[rawlabel] = keywords
+				defaults.Global.S_layout[rawlabel] = append(defaults.Global.S_layout[rawlabel], name)
+				comments = nil
+				last := len(keywords) - 1
+				setText = func(text string) { defaults.Global.S_keywords[rawlabel][last] = text }
+				continue
+			}
+			if len(comments) > 0 {
+				defaults.Global.S_comments[CommentKey(rawlabel, name)] = comments
+			}
+			comments = nil
+			if _, seen := keyval[name]; !seen {
+				keys = append(keys, name)
+				defaults.Global.S_layout[rawlabel] = append(defaults.Global.S_layout[rawlabel], name)
+			}
+			keyval[name] = value
+			setText = func(text string) { defaults.Global.S_text[CommentKey(rawlabel, name)] = text }
 		}
-		for key, val := range keyval {
-			key = strings.Replace(key, ".", "__", 1)
-			key = "S_" + key
-			val = strings.Trim(val, " \n")
-			val = strings.ReplaceAll(val, "yes", "true")
-			val = strings.ReplaceAll(val, "no", "false")
-			val = strings.ReplaceAll(val, "on", "true")
-			val = strings.ReplaceAll(val, "off", "false")
+		if len(comments) > 0 {
+			defaults.Global.S_comments[CommentKey(rawlabel, "}")] = comments
+		}
+		for _, name := range keys {
+			val := keyval[name]
+			key := optionField(name)
+			position := CommentKey(rawlabel, name)
 			fieldt, err := reflections.GetFieldType(section, key)
 			if err != nil {
-				fmt.Println("error parsing a field: "+key, err)
+				errs = append(errs, at.errorAt(position, false, "unknown option "+name+" in section "+rawlabel))
 				continue
 			}
-			if fieldt == "bool" {
-				parsed_val, err := strconv.ParseBool(val)
-				if err != nil {
-					fmt.Println("error parsing a type: "+fieldt+"  with data: "+val, err)
-					continue
-				}
-				err = reflections.SetField(section, key, parsed_val)
-				if err != nil {
-					fmt.Println("failed setting field: "+key+"  value: "+val+"||", err)
-					continue
-				}
-			} else if fieldt == "int64" {
+			parsed, err := parseValue(fieldt, val)
+			if err != nil {
+				errs = append(errs, at.errorAt(position, true, fmt.Sprintf("%s expects %s, found %q", name, typeNames[fieldt], val)))
+				continue
+			}
+			if err := reflections.SetField(section, key, parsed); err != nil {
+				errs = append(errs, at.errorAt(position, true, fmt.Sprintf("could not set %s: %v", name, err)))
+			}
+		}
+	}
+	return defaults, errs
+}
 
-				parsed_val, err := strconv.ParseInt(val, 6, 64)
-				if err != nil {
-					fmt.Println("error parsing a type: "+fieldt+"  with data: "+val, err)
-					continue
-				}
-				err = reflections.SetField(section, key, parsed_val)
-				if err != nil {
-					fmt.Println("failed setting field: "+key+"  value: "+val+"||", err)
-					continue
-				}
-			} else if fieldt == "float64" {
+// Parse the value of an option of the type fieldt, the type of its field in its props section struct
+func parseValue(fieldt string, val string) (interface{}, error) {
+	switch fieldt {
+	case "bool":
+		// only whole values are aliases, replacing inside strings would mangle them
+		switch val {
+		case "yes", "on":
+			val = "true"
+		case "no", "off":
+			val = "false"
+		}
+		return strconv.ParseBool(val)
+	case "int64":
+		return strconv.ParseInt(val, 10, 64)
+	case "float64":
+		return strconv.ParseFloat(val, 64)
+	case "[2]float64":
+		return parseVector(val)
+	}
+	return val, nil
+}
 
-				parsed_val, err := strconv.ParseFloat(val, 64)
-				if err != nil {
-					fmt.Println("error parsing a type: "+fieldt+"  with data: "+val, err)
-					continue
-				}
-				err = reflections.SetField(section, key, parsed_val)
-				if err != nil {
-					fmt.Println("failed setting field: "+key+"  value: "+val+"||", err)
-					continue
-				}
-			} else if fieldt == "[2]float64" {
-				vec := strings.Split(val, " ")
-				parsed_val := [2]int{0, 0}
-				for i, v := range vec[:2] {
-					parsed_v, err := strconv.ParseFloat(v, 64)
-					if err != nil {
-						fmt.Println("error parsing a type: "+fieldt+"  with data: "+val, err)
-						break
-					}
-					parsed_val[i] = int(parsed_v)
-				}
+// Keywords of sections that can be given more than once, so each line of them is kept as it is written
+var sectionKeywords = map[string]bool{
+	"bezier":    true,
+	"animation": true,
+}
 
-				err = reflections.SetField(section, key, parsed_val)
-				if err != nil {
-					fmt.Println("failed setting field: "+key+"  value: "+val+"||", err)
-					continue
-				}
-			} else {
-				err = reflections.SetField(section, key, val)
-				if err != nil {
-					fmt.Println("failed setting field(final else): "+key+"  with data: "+val, err)
-					continue
-				}
+// What the values of each type of option look like, for errors
+var typeNames = map[string]string{
+	"bool":       "true or false",
+	"int64":      "a whole number",
+	"float64":    "a number",
+	"[2]float64": "two numbers",
+}
+
+// Parse a value made of two numbers separated by a space, like shadow_offset = 2 2
+func parseVector(val string) ([2]float64, error) {
+	var vec [2]float64
+	fields := strings.Fields(val)
+	if len(fields) < 2 {
+		return vec, fmt.Errorf("expected two numbers")
+	}
+	for i, field := range fields[:2] {
+		n, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return vec, err
+		}
+		vec[i] = n
+	}
+	return vec, nil
+}
+
+// Return true if label is a per device section like device:epic-mouse-v1
+func isDeviceSection(label string) bool {
+	return strings.HasPrefix(label, "device:")
+}
+
+// Return the lines of a device section block as they are written in the config, and the line opening it
+// Lines the block has no text of are indented
+func deviceLines(block string) (string, string) {
+	var lines []string
+	open := ""
+	first := true
+	last := -1 // index in lines of the last line that isn't a comment, -1 without one
+	for _, line := range strings.Split(block, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, sectionMarker) {
+			continue
+		}
+		if text, ok := textLine(line); ok {
+			if first {
+				open = text
+			} else if last >= 0 {
+				lines[last] = text
 			}
+			first, last = false, -1
+			continue
+		}
+		first = false
+		if comment, ok := commentLine(line); ok {
+			lines = append(lines, comment)
+			last = -1
+			continue
 		}
+		lines = append(lines, "    "+line)
+		last = len(lines) - 1
 	}
-	return defaults
+	return buildComments(lines), open
 }
 
-func Parse(content string) props.Config {
-	content = ParseComments(content)
-	blocks := ParseBlocks(content)
-	return ParseConfig(blocks)
+// Return the section the section label is written in, nested sections are only known by their label
+func parentSection(label string) string {
+	if label == "touchpad" || label == "touchdevice" {
+		return "input"
+	}
+	return "global"
+}
+
+// Parse a config into its sections, binds and variables
+// The problems found in it are returned as errors with the line they are on.
+// Parsing goes on past them, a section that is never closed ends with the config
+func Parse(content string) (props.Config, []ParseError) {
+	// Configs saved on Windows end their lines with \r\n
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	at, errs := findPositions(content)
+	blocks, order := ParseBlocks(markComments(content))
+	conf, configErrs := parseConfig(blocks, order, at)
+	return conf, append(errs, configErrs...)
+}
+
+// formats a field value the way hyprland expects it
+func FormatValue(val interface{}) string {
+	if vec, ok := val.([2]float64); ok {
+		return fmt.Sprint(vec[0]) + " " + fmt.Sprint(vec[1])
+	}
+	return fmt.Sprint(val)
+}
+
+// Return comment lines as they are written in the config
+func buildComments(lines []string) string {
+	var out string
+	for _, line := range lines {
+		out += line + "\n"
+	}
+	return out
+}
+
+// Return line as the config wrote it, text, if unchanged says the code of text, the text without its comment, is still line
+// Otherwise line is written with the indentation and comment of text, and indented by indent without text
+func asWritten(line string, indent string, text string, unchanged func(code string) bool) string {
+	if text == "" {
+		return indent + line + "\n"
+	}
+	rest := strings.TrimLeft(text, " \t")
+	code, comment := strings.TrimRight(rest, " \t"), ""
+	if i := keybinds.CommentIndex(rest); i >= 0 {
+		code = strings.TrimRight(rest[:i], " \t")
+		comment = rest[len(code):]
+	}
+	if unchanged(code) {
+		return text + "\n"
+	}
+	return text[:len(text)-len(rest)] + line + comment + "\n"
+}
+
+// Return true if code is the line opening the section label
+func opensSection(label string) func(code string) bool {
+	return func(code string) bool {
+		return strings.HasSuffix(code, "{") && strings.TrimSpace(strings.TrimSuffix(code, "{")) == label
+	}
+}
+
+// Return true if code is the line closing a section
+func closesSection(code string) bool {
+	return code == "}"
 }
 
+// Return the bind entry of S_binds at index as it is written in the config
+func buildBind(glob props.S_global, index int) string {
+	var out string
+	for key, val := range glob.S_binds[index] {
+		if key == CommentKeyword {
+			out += buildComments(val)
+			continue
+		}
+		out += asWritten(key+" = "+strings.Join(val, ","), "", glob.S_text[bindTextKey(index)], func(code string) bool {
+			name, value := optionValue(code)
+			return name == key && reflect.DeepEqual(strings.Split(value, ","), val)
+		})
+	}
+	return out
+}
+
+// Return a variable of the global block as it is written in the config, with the comments above it
+func buildVariable(glob props.S_global, name string) string {
+	value := glob.S_variables[name]
+	return buildComments(glob.S_comments[CommentKey("global", name)]) +
+		asWritten(name+" = "+value, "", glob.S_text[CommentKey("global", name)], func(code string) bool {
+			parts := strings.SplitN(code, "=", 2)
+			return len(parts) == 2 && strings.Trim(parts[0], " ") == name && strings.Trim(parts[1], " ") == value
+		})
+}
+
+// Return the lines of S_raw, each without its newline
+func rawLines(raw string) []string {
+	if raw == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(raw, "\n"), "\n")
+}
+
+// Return the global block as it is written in the config, without its sections
+// Variables, binds and keywords are written in the order of S_layout, the ones it doesn't have after them
 func BuildGlobal(glob props.S_global) string {
+	return buildGlobal(glob, func(string) string { return "" })
+}
+
+// Write the global block in the order of S_layout, its sections written by section
+func buildGlobal(glob props.S_global, section func(label string) string) string {
 	var out string
-	for key, val := range glob.S_variables {
-		out += key + " = " + val + "\n"
+	written := make(map[string]bool)
+	binds := 0 // index of the next bind to write in S_binds
+	raw := rawLines(glob.S_raw)
+	for _, entry := range glob.S_layout["global"] {
+		switch {
+		case entry == layoutBind:
+			if binds < len(glob.S_binds) {
+				out += buildBind(glob, binds)
+				binds++
+			}
+		case entry == layoutRaw:
+			if len(raw) > 0 {
+				out += raw[0] + "\n"
+				raw = raw[1:]
+			}
+		case strings.HasPrefix(entry, sectionMarker):
+			out += section(strings.TrimPrefix(entry, sectionMarker))
+		default:
+			if _, ok := glob.S_variables[entry]; ok {
+				out += buildVariable(glob, entry)
+				written[entry] = true
+			}
+		}
 	}
-	out += "\n"
-	for _, binds := range glob.S_binds {
-		for key, val := range binds {
-			out += key + " = " + strings.Join(val, ",") + "\n"
+
+	// sorted, so the same config always builds the same output
+	var names []string
+	for name := range glob.S_variables {
+		if !written[name] {
+			names = append(names, name)
 		}
 	}
-	out += glob.S_raw
+	sort.Strings(names)
+	for _, name := range names {
+		out += buildVariable(glob, name)
+	}
+	for ; binds < len(glob.S_binds); binds++ {
+		out += buildBind(glob, binds)
+	}
+	for _, line := range raw {
+		out += line + "\n"
+	}
 	return out
 }
 
-func BuildConf(conf props.Config) string {
-	output := "#-----------------------------#\n#    Generated By HyprKeys    #\n#-----------------------------#\n\n"
-	fields, err := reflections.Fields(conf)
+// Return the fields of a config in the order its sections were written in,
+// Global first and the sections the config didn't have last
+// The labels of device sections, which aren't fields, are kept where the config has them
+func sectionOrder(fields []string, order []string, devices map[string]string) []string {
+	var sorted []string
+	for _, field := range fields {
+		if field == "Global" {
+			sorted = append(sorted, field)
+		}
+	}
+	for _, label := range order {
+		if _, ok := devices[label]; ok {
+			sorted = append(sorted, label)
+			continue
+		}
+		for _, field := range fields {
+			if strings.EqualFold(field, label) && !contains(sorted, field) {
+				sorted = append(sorted, field)
+			}
+		}
+	}
+	for _, field := range fields {
+		if !contains(sorted, field) {
+			sorted = append(sorted, field)
+		}
+	}
+	return sorted
+}
+
+// Return the section of conf labeled label as it is written in the config, with the comments above it
+func buildSection(conf props.Config, label string) (string, error) {
+	glob := conf.Global
+	comments := buildComments(glob.S_comments[CommentKey("global", label)])
+	open := asWritten(label+" {", "", glob.S_text[CommentKey("global", sectionMarker+label)], opensSection(label))
+	close := asWritten("}", "", glob.S_text[CommentKey(label, "}")], closesSection)
+	if lines, ok := glob.S_devices[label]; ok {
+		return comments + open + lines + close, nil
+	}
+	// unknown sections aren't kept, Parse returns them as errors
+	if label == "" {
+		return "", nil
+	}
+	block, err := reflections.GetField(conf, sectionField(label))
+	if err != nil {
+		return "", nil
+	}
+	defaults, err := reflections.GetField(props.NewConf(), sectionField(label))
+	if err != nil {
+		return "", fmt.Errorf("getting the defaults of section %s: %w", label, err)
+	}
+	options, err := buildBlock(*glob, label, block, defaults, "    ")
 	if err != nil {
-		fmt.Println("error getting fields", err)
+		return "", err
+	}
+	return comments + open + options + close, nil
+}
+
+// Return what the section label has, block holding its options and defaults their defaults, indented by indent
+// Its options, keywords and nested sections are written in the order of its S_layout,
+// and the options and nested sections it doesn't have after them if they were changed from their defaults
+func buildBlock(glob props.S_global, label string, block interface{}, defaults interface{}, indent string) (string, error) {
+	fields, err := reflections.Fields(block)
+	if err != nil {
+		return "", fmt.Errorf("getting the options of section %s: %w", label, err)
+	}
+	// what the config has, and after it the options it didn't have that were changed from their defaults
+	var names []string
+	for _, entry := range glob.S_layout[label] {
+		name := strings.TrimPrefix(entry, sectionMarker)
+		if sectionKeywords[name] || (contains(fields, optionField(name)) && !contains(names, name)) {
+			names = append(names, name)
+		}
 	}
 	for _, field := range fields {
-		block, err := reflections.GetField(conf, field)
+		name := propertyName(field)
+		if contains(names, name) {
+			continue
+		}
+		val, err := reflections.GetField(block, field)
 		if err != nil {
-			fmt.Println("error getting block", err)
 			continue
 		}
-		field = strings.Replace(field, "S_", "", 1)
-		field = strings.ToLower(field)
-		if field == "global" {
-			output += BuildGlobal(*conf.Global)
+		if def, err := reflections.GetField(defaults, field); err != nil || !reflect.DeepEqual(val, def) {
+			names = append(names, name)
+		}
+	}
+
+	var out string
+	keywords := glob.S_keywords[label]
+	for _, name := range names {
+		if sectionKeywords[name] {
+			var lines []string
+			lines, keywords = nextKeyword(keywords)
+			out += buildComments(lines)
 			continue
 		}
-		output += field
-		output += " {\n"
-		block_fields, err := reflections.Fields(block)
+		val, err := reflections.GetField(block, optionField(name))
 		if err != nil {
-			fmt.Println("error getting block fields", err)
+			return "", fmt.Errorf("getting option %s of section %s: %w", name, label, err)
+		}
+		comments := buildComments(glob.S_comments[CommentKey(label, name)])
+		if _, err := reflections.Fields(val); err != nil {
+			fieldt, _ := reflections.GetFieldType(block, optionField(name))
+			line := strings.TrimRight(name+" = "+FormatValue(val), " ")
+			out += comments + asWritten(line, indent, glob.S_text[CommentKey(label, name)], func(code string) bool {
+				written, value := optionValue(code)
+				parsed, err := parseValue(fieldt, value)
+				return written == name && err == nil && reflect.DeepEqual(parsed, val)
+			})
 			continue
 		}
-		for _, block_field := range block_fields {
-			val, err := reflections.GetField(block, block_field)
-			if err != nil {
-				fmt.Println("error getting block field", err)
-				continue
-			}
-			block_field = strings.Replace(block_field, "__", ".", 1)
-			block_field = strings.TrimPrefix(block_field, "S_")
-			val_fields, err := reflections.Fields(val)
-			if val_fields == nil || err != nil {
-				fmt.Println(val_fields, err)
-				output += "    " + block_field + " = " + fmt.Sprint(val) + "\n"
-			} else {
-				fmt.Println("got field: ", val_fields)
-				output += "    " + block_field + " {\n"
-				for _, val_field := range val_fields {
-					val_val, err := reflections.GetField(val, val_field)
-					if err != nil {
-						fmt.Println("error getting block field", err)
-						continue
-					}
-					val_field = strings.Replace(val_field, "__", ".", 1)
-					val_field = strings.TrimPrefix(val_field, "S_")
-					output += "        " + val_field + " = " + fmt.Sprint(val_val) + "\n"
-				}
-				output += "    }\n"
-			}
+		def, err := reflections.GetField(defaults, optionField(name))
+		if err != nil {
+			def = nil
+		}
+		options, err := buildBlock(glob, name, val, def, indent+"    ")
+		if err != nil {
+			return "", err
 		}
-		output += "}\n\n"
+		out += comments + asWritten(name+" {", indent, glob.S_text[CommentKey(label, sectionMarker+name)], opensSection(name)) +
+			options + asWritten("}", indent, glob.S_text[CommentKey(name, "}")], closesSection)
 	}
-	return output
+	out += buildComments(keywords)
+	return out + buildComments(glob.S_comments[CommentKey(label, "}")]), nil
+}
+
+// Return the lines of the first keyword in the keyword lines of a section, the comments above it included, and the rest
+func nextKeyword(lines []string) ([]string, []string) {
+	for i, line := range lines {
+		if line := strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			return lines[:i+1], lines[i+1:]
+		}
+	}
+	return lines, nil
+}
+
+// Return the field of a props section struct holding the option name, e.g. col.active_border -> S_col__active_border
+func optionField(name string) string {
+	return "S_" + strings.Replace(name, ".", "__", 1)
+}
+
+// Return the field of props.Config holding the section label
+func sectionField(label string) string {
+	return strings.ToUpper(label[:1]) + label[1:]
+}
+
+// Return true if the section label of conf differs from its defaults
+func sectionChanged(conf props.Config, label string) bool {
+	block, err := reflections.GetField(conf, sectionField(label))
+	if err != nil {
+		return false
+	}
+	defaults, err := reflections.GetField(props.NewConf(), sectionField(label))
+	return err != nil || !reflect.DeepEqual(block, defaults)
+}
+
+// Comment lines BuildConf starts a config with, ParseGlobal leaves them out so they aren't written twice
+var generatedHeader = []string{
+	"#-----------------------------#",
+	"#    Generated By HyprKeys    #",
+	"#-----------------------------#",
+}
+
+// Return the lines of the global block without the header BuildConf starts a config with, see generatedHeader
+// The blank line BuildConf writes after it goes with it
+func withoutHeader(lines []string) []string {
+	header := len(lines) >= len(generatedHeader)
+	for i := 0; header && i < len(generatedHeader); i++ {
+		header = strings.TrimSpace(lines[i]) == commentMarker+generatedHeader[i]
+	}
+	if !header {
+		return lines
+	}
+	lines = lines[len(generatedHeader):]
+	if len(lines) > 0 && strings.TrimSpace(lines[0]) == commentMarker {
+		lines = lines[1:]
+	}
+	return lines
+}
+
+// Build the config conf was parsed from after the header, see generatedHeader, Parse(BuildConf(conf)) gives back conf
+// Lines whose values didn't change are written as the config wrote them, comments and blank lines included.
+// Everything is written where the config had it, sections it didn't have only if they were changed from their defaults
+// Everything is written where the config had it, sections it didn't have only if they were changed from their defaults
+func BuildConf(conf props.Config) (string, error) {
+	fields, err := reflections.Fields(conf)
+	if err != nil {
+		return "", fmt.Errorf("getting the sections: %w", err)
+	}
+	written := make(map[string]bool)
+	// the first error building a section, buildGlobal writes them as it goes
+	var sectionErr error
+	section := func(label string) string {
+		out, err := buildSection(conf, label)
+		if err != nil && sectionErr == nil {
+			sectionErr = err
+		}
+		return out
+	}
+	output := buildGlobal(*conf.Global, func(label string) string {
+		written[strings.ToLower(label)] = true
+		return section(label)
+	})
+	for _, field := range sectionOrder(fields, conf.Global.S_order, conf.Global.S_devices) {
+		label := strings.ToLower(strings.TrimPrefix(field, "S_"))
+		if field == "Global" || written[label] {
+			continue
+		}
+		if _, device := conf.Global.S_devices[field]; device {
+			output += "\n" + section(field)
+		} else if sectionChanged(conf, label) {
+			output += "\n" + section(label)
+		}
+	}
+	if sectionErr != nil {
+		return "", sectionErr
+	}
+	output += buildComments(conf.Global.S_comments[CommentKey("global", "}")])
+
+	return strings.Join(generatedHeader, "\n") + "\n\n" + output, nil
 }
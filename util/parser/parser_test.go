@@ -0,0 +1,190 @@
+package parser
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"notashelf.dev/hyprkeys/util/keybinds"
+	props "notashelf.dev/hyprkeys/util/properties"
+)
+
+// Parse content, failing the test on errors
+func mustParse(t *testing.T, content string) props.Config {
+	t.Helper()
+	conf, errs := Parse(content)
+	if len(errs) > 0 {
+		t.Fatalf("Parse: %v", errs)
+	}
+	return conf
+}
+
+// Build conf, failing the test on errors
+func mustBuild(t *testing.T, conf props.Config) string {
+	t.Helper()
+	built, err := BuildConf(conf)
+	if err != nil {
+		t.Fatalf("BuildConf: %v", err)
+	}
+	return built
+}
+
+func TestBuildConfRoundTrip(t *testing.T) {
+	sample, err := os.ReadFile("../../test/hyprland.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"sample config", string(sample)},
+		{"comment headers between sections", "# general\ngeneral {\n    gaps_in = 5\n}\n\n# ---- input ----\n\ninput {\n    # layout\n    kb_layout = us\n\n    touchpad {\n        natural_scroll = yes\n    }\n}\n"},
+		{"keywords given more than once", "animations {\n    enabled = yes\n    bezier = a, 0, 0, 1, 1\n\n    # windows\n    animation = windows, 1, 7, a\n    animation = fade, 1, 7, default\n}\n"},
+		{"device sections", "# mouse\ndevice:epic-mouse-v1 {\n    sensitivity = -0.5\n}\n"},
+		{"variables in file order", "$b = 2\n$a = 1\nbind = $a, Q, exec, $b\n"},
+		{"comments before the binds", "# header\n\nmonitor=,preferred,auto,1\n# binds\nbind = SUPER, Q, exec, kitty\n"},
+		{"hash in quotes", "bind = SUPER, Q, exec, notify-send \"#1\" # comment\n"},
+		{"submaps", "submap = resize\nbinde = , right, resizeactive, 10 0\nsubmap = reset\n"},
+		{"crlf line endings", "$mod = SUPER\r\nbind = $mod, Q, exec, kitty\r\n"},
+		{"trailing comments", "$mod = SUPER # main\nmonitor=,preferred,auto,1 # any\ngeneral { # layout\n    gaps_in = 5 # inner\n    col.active_border = rgba(33ccffee) # active\n} # general\nbind = $mod, Q, killactive # close\ndevice:epic-mouse-v1 {\n    sensitivity = -0.5 # slow\n}\n"},
+		{"runs of blank lines", "\n\n$mod = SUPER\n\n\n\ngeneral {\n\n\n    gaps_in = 5\n\n\n}\n\n\nbind = $mod, Q, killactive\n\n\n"},
+		{"values written their own way", "input {\n    numlock_by_default = on\n    touchpad {\n        natural_scroll = yes\n    }\n}\ngeneral {\n\tgaps_in=5\n  border_size =   2  \n}\n"},
+		{"blanks after lines", "$mod = SUPER  \nbind = $mod, Q, killactive\t\n# comment  \n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := mustParse(t, tt.content)
+			built := mustBuild(t, conf)
+			content := strings.ReplaceAll(tt.content, "\r\n", "\n")
+			if !strings.HasSuffix(content, "\n") {
+				content += "\n"
+			}
+			if want := strings.Join(generatedHeader, "\n") + "\n\n" + content; built != want {
+				t.Errorf("BuildConf(Parse(x)) isn't x, got:\n%s\nwant:\n%s", built, want)
+			}
+			rebuilt := mustParse(t, built)
+			if again := mustBuild(t, rebuilt); again != built {
+				t.Errorf("BuildConf of its own output changed it, first:\n%s\nthen:\n%s", built, again)
+			}
+		})
+	}
+}
+
+func TestBuildConfKeepsOrder(t *testing.T) {
+	conf := mustParse(t, "# first\nmonitor=,preferred,auto,1\n$b = 2\n$a = 1\n\ngeneral {\n    gaps_in = 5\n}\nbind = $a, Q, exec, $b\n")
+	built := mustBuild(t, conf)
+	want := []string{"# first", "monitor=", "$b = 2", "$a = 1", "general {", "bind = $a"}
+	last := -1
+	for _, line := range want {
+		i := strings.Index(built, line)
+		if i <= last {
+			t.Fatalf("%q isn't after what comes before it in the config:\n%s", line, built)
+		}
+		last = i
+	}
+	for _, section := range []string{"misc {", "debug {", "binds {", "touchdevice {"} {
+		if strings.Contains(built, section) {
+			t.Errorf("BuildConf wrote %s, which the config doesn't have:\n%s", section, built)
+		}
+	}
+	if n := strings.Count(built, generatedHeader[1]); n != 1 {
+		t.Errorf("header written %d times:\n%s", n, built)
+	}
+}
+
+func TestParseOrderStable(t *testing.T) {
+	content := "input {\n    kb_layout = us\n    touchpad {\n        natural_scroll = yes\n    }\n}\ndecoration {\n    rounding = 10\n}\ngeneral {\n    gaps_in = 5\n    border_size = 2\n}\n$b = 2\n$a = 1\nbind = $a, Q, exec, $b\n"
+	conf := mustParse(t, content)
+	wantOrder := []string{"input", "touchpad", "decoration", "general"}
+	if !reflect.DeepEqual(conf.Global.S_order, wantOrder) {
+		t.Errorf("got sections %q, want them in file order %q", conf.Global.S_order, wantOrder)
+	}
+	built := mustBuild(t, conf)
+	encoded, err := json.Marshal(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Go randomizes map iteration, the output must not depend on it
+	for i := 0; i < 20; i++ {
+		again := mustParse(t, content)
+		if got := mustBuild(t, again); got != built {
+			t.Fatalf("BuildConf changed between runs, first:\n%s\nthen:\n%s", built, got)
+		}
+		if got, _ := json.Marshal(again); string(got) != string(encoded) {
+			t.Fatalf("JSON changed between runs, first:\n%s\nthen:\n%s", encoded, got)
+		}
+	}
+	if input, general := strings.Index(built, "input {"), strings.Index(built, "general {"); input < 0 || general < input {
+		t.Errorf("sections aren't written in file order:\n%s", built)
+	}
+}
+
+func TestBuildConfKeepsSubmaps(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"submap block", "bind = SUPER, R, submap, resize\nsubmap = resize\nbinde = , right, resizeactive, 10 0\nbind = , escape, submap, reset\nsubmap = reset\nbind = SUPER, Q, killactive\n"},
+		{"two submaps", "submap = a\nbind = , A, exec, a\nsubmap = reset\nsubmap = b\nbind = , B, exec, b\nsubmap = reset\n"},
+		{"submap with comments", "# resize mode\nsubmap = resize # enter\n    # grow\n    binde = , right, resizeactive, 10 0\nsubmap = reset\n"},
+		{"never reset", "bind = , A, exec, a\nsubmap = resize\nbind = , B, exec, b\n"},
+	}
+	// The submap of each bind, as hyprkeys reads it
+	submaps := func(content string) []string {
+		c, err := keybinds.Parse([]byte(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, kb := range c.Keybinds {
+			got = append(got, kb.Submap+":"+kb.Keys())
+		}
+		return got
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			built := mustBuild(t, mustParse(t, tt.content))
+			if got, want := submaps(built), submaps(tt.content); !reflect.DeepEqual(got, want) {
+				t.Errorf("got binds %q, want %q, built:\n%s", got, want, built)
+			}
+		})
+	}
+}
+
+func TestBuildConfKeepsComments(t *testing.T) {
+	header := strings.Join(generatedHeader, "\n") + "\n\n"
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"headers between sections", "# ---- general ----\ngeneral {\n    gaps_in = 5\n}\n\n# ---- input ----\n\ninput {\n    kb_layout = us\n}\n"},
+		{"comments and blank lines in a section", "general {\n    gaps_in = 5\n\n    # borders\n    border_size = 2\n}\n"},
+		{"comments around the binds", "# binds\nbind = SUPER, Q, killactive\n\n# apps\nbind = SUPER, T, exec, kitty\n"},
+		{"a comment block", "# one\n# two\n# three\n\nbind = SUPER, Q, killactive\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			built := mustBuild(t, mustParse(t, tt.content))
+			if want := header + tt.content; built != want {
+				t.Errorf("got:\n%s\nwant:\n%s", built, want)
+			}
+		})
+	}
+}
+
+func TestBuildConfKeepsCommentsOfChangedValues(t *testing.T) {
+	conf := mustParse(t, "$mod = SUPER # main\ngeneral {\n    gaps_in = 5   # gaps\n    border_size = 2\n}\nbind = $mod, Q, killactive # close\n")
+	conf.General.S_gaps_in = 10
+	conf.General.S_border_size = 3
+	conf.Global.S_variables["$mod"] = "ALT"
+	conf.Global.S_binds[0]["bind"] = []string{"$mod", " W", " killactive"}
+	built := mustBuild(t, conf)
+	for _, line := range []string{"$mod = ALT # main\n", "    gaps_in = 10   # gaps\n", "    border_size = 3\n", "bind = $mod, W, killactive # close\n"} {
+		if !strings.Contains(built, line) {
+			t.Errorf("%q not in:\n%s", line, built)
+		}
+	}
+}
@@ -169,6 +169,21 @@ type S_global struct {
 	S_binds     []map[string][]string
 	S_variables map[string]string
 	S_raw       string
+	S_order     []string // labels of the blocks in the order the config opens them
+	// comment lines written above options, variables and sections as written, "" for blank lines, see parser.CommentKey
+	// Comments above binds are kept among S_binds, and the ones above other keywords in S_raw
+	S_comments map[string][]string
+	// per device sections like device:epic-mouse-v1 by label, their lines as written, they aren't parsed
+	S_devices map[string]string
+	// lines of the keywords given once per line in a section, like bezier and animation, by section label
+	// Comment lines above them are kept among them, "" for blank lines
+	S_keywords map[string][]string
+	// what each section has, in the order the config has it, by section label and "global" for the global block
+	// see parser.BuildConf
+	S_layout map[string][]string
+	// lines of options, variables, binds and the braces of sections as the config writes them, comments after them and all,
+	// by parser.CommentKey and binds by their index in S_binds. BuildConf writes them back unless their values changed
+	S_text map[string]string
 }
 
 func NewGlobal() *S_global {
@@ -176,6 +191,11 @@ func NewGlobal() *S_global {
 		S_binds:     make([]map[string][]string, 0),
 		S_variables: make(map[string]string),
 		S_raw:       "",
+		S_comments:  make(map[string][]string),
+		S_devices:   make(map[string]string),
+		S_keywords:  make(map[string][]string),
+		S_layout:    make(map[string][]string),
+		S_text:      make(map[string]string),
 	}
 }
 
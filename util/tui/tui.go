@@ -0,0 +1,179 @@
+// Package tui is the interactive keybind browser of --tui
+package tui
+
+import (
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+	keybinds "notashelf.dev/hyprkeys/util/keybinds"
+)
+
+// ANSI escapes the browser is drawn with
+const (
+	altScreen    = "\x1b[?1049h\x1b[?25l"
+	normalScreen = "\x1b[?25h\x1b[?1049l"
+	clearScreen  = "\x1b[H\x1b[2J"
+	reverse      = "\x1b[7m"
+	bold         = "\x1b[1m"
+	reset        = "\x1b[0m"
+)
+
+// Lines taken by the search line at the top and the details of the selected bind at the bottom
+const chromeLines = 5
+
+// State of the browser
+type browser struct {
+	keybinds []keybinds.Keybind
+	query    string
+	matches  []keybinds.Keybind // binds matching the query
+	selected int                // index into matches
+	offset   int                // index of the first match on screen
+}
+
+// Show a scrollable list of the binds that typing filters, until Esc or Ctrl-C is pressed
+// Up and Down (or Ctrl-P and Ctrl-N) select a bind, its full command and where it is written are shown below the list.
+// stdin and stdout have to be a terminal
+func Run(binds []keybinds.Keybind) error {
+	fd := int(os.Stdin.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(fd, state)
+	os.Stdout.WriteString(altScreen)
+	defer os.Stdout.WriteString(normalScreen)
+
+	b := &browser{keybinds: binds}
+	b.filter()
+	buf := make([]byte, 32)
+	for {
+		width, height, err := term.GetSize(int(os.Stdout.Fd()))
+		if err != nil {
+			return err
+		}
+		os.Stdout.WriteString(b.render(width, height))
+
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return err
+		}
+		if quit := b.handle(buf[:n], height-chromeLines); quit {
+			return nil
+		}
+	}
+}
+
+// Update the browser for the bytes read from the terminal, return true to quit
+// rows is how many binds fit on the screen
+func (b *browser) handle(input []byte, rows int) bool {
+	switch string(input) {
+	case "\x1b", "\x03":
+		return true
+	case "\x1b[A", "\x1bOA", "\x10":
+		b.move(-1, rows)
+	case "\x1b[B", "\x1bOB", "\x0e":
+		b.move(1, rows)
+	case "\x1b[5~":
+		b.move(-rows, rows)
+	case "\x1b[6~":
+		b.move(rows, rows)
+	case "\x7f", "\x08":
+		if b.query != "" {
+			_, size := utf8.DecodeLastRuneInString(b.query)
+			b.query = b.query[:len(b.query)-size]
+			b.filter()
+		}
+	case "\x15":
+		b.query = ""
+		b.filter()
+	default:
+		text := string(input)
+		if !utf8.ValidString(text) || strings.IndexFunc(text, unicode.IsControl) >= 0 {
+			return false
+		}
+		b.query += text
+		b.filter()
+	}
+	return false
+}
+
+// Select the bind delta rows away, scrolling the list so it stays on screen
+func (b *browser) move(delta int, rows int) {
+	b.selected += delta
+	if b.selected >= len(b.matches) {
+		b.selected = len(b.matches) - 1
+	}
+	if b.selected < 0 {
+		b.selected = 0
+	}
+	if b.selected < b.offset {
+		b.offset = b.selected
+	}
+	if rows > 0 && b.selected >= b.offset+rows {
+		b.offset = b.selected - rows + 1
+	}
+}
+
+// Keep the binds matching the query, compared case-insensitively with their keys, action and description
+func (b *browser) filter() {
+	query := strings.ToLower(b.query)
+	b.matches = nil
+	for _, kb := range b.keybinds {
+		text := strings.ToLower(kb.Keys() + " " + kb.Action() + " " + kb.Description)
+		if strings.Contains(text, query) {
+			b.matches = append(b.matches, kb)
+		}
+	}
+	b.selected = 0
+	b.offset = 0
+}
+
+// Return the screen for a terminal of the given size
+func (b *browser) render(width int, height int) string {
+	lines := []string{bold + "Search: " + reset + b.query, ""}
+
+	rows := height - chromeLines
+	keysWidth := 0
+	for _, kb := range b.matches {
+		if n := utf8.RuneCountInString(kb.Keys()); n > keysWidth {
+			keysWidth = n
+		}
+	}
+	for i := b.offset; i < len(b.matches) && i < b.offset+rows; i++ {
+		kb := b.matches[i]
+		keys := kb.Keys() + strings.Repeat(" ", keysWidth-utf8.RuneCountInString(kb.Keys()))
+		line := cut(keys+"  "+kb.Dispatcher+" "+kb.DisplayCommand(), width)
+		if i == b.selected {
+			line = reverse + line + strings.Repeat(" ", width-utf8.RuneCountInString(line)) + reset
+		}
+		lines = append(lines, line)
+	}
+	for len(lines) < height-chromeLines+2 {
+		lines = append(lines, "")
+	}
+
+	lines = append(lines, strings.Repeat("─", width))
+	if len(b.matches) == 0 {
+		lines = append(lines, "No binds match")
+	} else {
+		kb := b.matches[b.selected]
+		details := kb.Action()
+		if kb.Description != "" {
+			details = kb.Description + ": " + details
+		}
+		lines = append(lines, cut(details, width), cut(kb.Location()+"  "+strings.TrimSpace(kb.Line), width))
+	}
+	// Raw mode doesn't turn \n into \r\n
+	return clearScreen + strings.Join(lines, "\r\n")
+}
+
+// Cut s down to width characters
+func cut(s string, width int) string {
+	if width < 0 || utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	return string([]rune(s)[:width])
+}
@@ -0,0 +1,71 @@
+package watch
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// How long to wait for more changes before running again
+// Editors often write a file several times in a row when saving it
+const debounce = 100 * time.Millisecond
+
+// Call run, then call it again whenever one of the files it returned changes
+// run returns the files to watch until the next time it is called.
+// The directories of the files are watched rather than the files themselves,
+// so a file replaced by an editor saving it is still picked up
+func Watch(run func() []string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	var files map[string]bool
+	dirs := make(map[string]bool)
+	update := func(paths []string) error {
+		files = make(map[string]bool)
+		for _, path := range paths {
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				return err
+			}
+			files[abs] = true
+			if dir := filepath.Dir(abs); !dirs[dir] {
+				if err := watcher.Add(dir); err != nil {
+					return err
+				}
+				dirs[dir] = true
+			}
+		}
+		return nil
+	}
+
+	if err := update(run()); err != nil {
+		return err
+	}
+
+	var timer <-chan time.Time
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op != fsnotify.Chmod && files[filepath.Clean(event.Name)] {
+				timer = time.After(debounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			return err
+		case <-timer:
+			timer = nil
+			if err := update(run()); err != nil {
+				return err
+			}
+		}
+	}
+}